@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +9,8 @@ import (
 	"school-website/internal/config"
 	"school-website/internal/database"
 	"school-website/internal/router"
+	"school-website/internal/services"
+	"school-website/internal/storage"
 )
 
 func main() {
@@ -17,15 +20,29 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Storage for uploaded files
+	store, err := newStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+
 	// Initialize database
-	db, err := database.New(cfg.DatabasePath)
+	db, err := database.New(cfg.DatabasePath, store, cfg.UploadDir)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
+	if !db.FullTextSearch() {
+		log.Fatalf("SQLite is built without FTS5, which document search needs: build the server with -tags sqlite_fts5")
+	}
+
+	// Create the first admin account from ADMIN_USERNAME/ADMIN_PASSWORD if there are no users yet
+	if err := services.NewUserService(db).EnsureBootstrapUser(cfg.AdminUsername, cfg.AdminPassword); err != nil {
+		log.Fatalf("Failed to bootstrap admin user: %v", err)
+	}
 
 	// Setup router
-	r := router.Setup(cfg, db)
+	r := router.Setup(cfg, db, store)
 
 	// Create custom server with increased request size limit (500 MB)
 	// This allows for multiple large file uploads
@@ -38,7 +55,6 @@ func main() {
 
 	log.Printf("Starting server on http://localhost%s", server.Addr)
 	log.Printf("Admin panel available at http://localhost%s/admin/login.html", server.Addr)
-	log.Printf("Login: %s, Password: %s", cfg.AdminUsername, cfg.AdminPassword)
 	log.Printf("Max request size: 500MB (for file uploads)")
 
 	if err := server.ListenAndServe(); err != nil {
@@ -46,6 +62,27 @@ func main() {
 	}
 }
 
+// newStorage creates the storage backend selected by STORAGE_BACKEND
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		return storage.NewLocal(cfg.UploadDir), nil
+	case "s3":
+		log.Printf("Uploads are stored in S3 bucket %s at %s", cfg.S3Bucket, cfg.S3Endpoint)
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+			Prefix:    cfg.S3Prefix,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, expected local or s3", cfg.StorageBackend)
+	}
+}
+
 func initDirectories() {
 	dirs := []string{
 		"public/uploads",
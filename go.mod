@@ -3,9 +3,20 @@ module school-website
 go 1.19
 
 require (
+	github.com/chai2010/webp v1.1.1
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/sessions v1.2.1
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
 	github.com/mattn/go-sqlite3 v1.14.17
+	github.com/microcosm-cc/bluemonday v1.0.25
+	github.com/yuin/goldmark v1.5.6
+	golang.org/x/crypto v0.14.0
+	golang.org/x/image v0.12.0
 )
 
-require github.com/gorilla/securecookie v1.1.1 // indirect
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
+	github.com/gorilla/securecookie v1.1.1 // indirect
+	golang.org/x/net v0.12.0 // indirect
+)
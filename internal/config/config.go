@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -14,8 +15,47 @@ type Config struct {
 	UploadDir     string
 	PublicDir     string
 	TemplatesDir  string
+	SiteURL       string // public base URL, e.g. https://school.kz; derived from the request when empty
+	SiteName      string
+	ImageTypes    []string // MIME types accepted for news images
+	DocumentTypes []string // MIME types accepted for documents
+
+	// Addresses (IPs or CIDRs) of reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers are believed; empty means none
+	TrustedProxies []string
+
+	// Storage for uploaded files: "local" (UploadDir) or "s3"
+	StorageBackend string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PathStyle    bool // bucket in the URL path, needed for MinIO
+	S3Prefix       string
 }
 
+// Default upload allow-lists; the types are detected from the file content
+var (
+	defaultImageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+	defaultDocumentTypes = []string{
+		"application/pdf",
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.ms-excel",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.text",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"application/vnd.oasis.opendocument.presentation",
+		"text/plain",
+		"application/zip",
+		"image/jpeg", "image/png", "image/gif", "image/webp",
+	}
+)
+
 func Load() *Config {
 	// Load from environment variables or use defaults
 	sessionKey := os.Getenv("SESSION_KEY")
@@ -33,6 +73,21 @@ func Load() *Config {
 		UploadDir:     "public/uploads",
 		PublicDir:     "public",
 		TemplatesDir:  "templates",
+		SiteURL:       strings.TrimRight(os.Getenv("SITE_URL"), "/"),
+		SiteName:      getEnv("SITE_NAME", "Начальная школа Академия"),
+		ImageTypes:    getEnvList("UPLOAD_IMAGE_TYPES", defaultImageTypes),
+		DocumentTypes: getEnvList("UPLOAD_DOCUMENT_TYPES", defaultDocumentTypes),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+
+		StorageBackend: getEnv("STORAGE_BACKEND", "local"),
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle:    getEnv("S3_PATH_STYLE", "true") != "false",
+		S3Prefix:       os.Getenv("S3_PREFIX"),
 	}
 }
 
@@ -42,3 +97,17 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma-separated list, e.g. "application/pdf, image/*"
+func getEnvList(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"school-website/internal/models"
+)
+
+// --- Audit Log Operations ---
+
+func (d *Database) SaveAuditEntry(entry models.AuditEntry) error {
+	insertSQL := `INSERT INTO audit_log(user_id, username, action, entity_type, entity_id, before_data, after_data, ip, created_at)
+                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := d.db.Exec(insertSQL,
+		entry.UserID,
+		entry.Username,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		nullableJSON(entry.Before),
+		nullableJSON(entry.After),
+		entry.IP,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving audit entry: %v", err)
+	}
+
+	return nil
+}
+
+// GetAuditEntries returns the audit log entries matching the filter, newest
+// first, together with the total number of matching entries.
+func (d *Database) GetAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.Username != "" {
+		conditions = append(conditions, "username = ? COLLATE NOCASE")
+		args = append(args, filter.Username)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM audit_log "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting audit entries: %v", err)
+	}
+
+	query := `SELECT id, user_id, username, action, entity_type, entity_id,
+			  COALESCE(before_data, ''), COALESCE(after_data, ''), COALESCE(ip, ''), created_at
+			  FROM audit_log ` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+
+	rows, err := d.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetAuditEntries query failed: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Username, &e.Action, &e.EntityType, &e.EntityID,
+			&before, &after, &e.IP, &e.CreatedAt); err != nil {
+			log.Printf("Error scanning audit entry: %v", err)
+			continue
+		}
+		if before != "" {
+			e.Before = []byte(before)
+		}
+		if after != "" {
+			e.After = []byte(after)
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit entries: %v", err)
+	}
+
+	return entries, total, nil
+}
+
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
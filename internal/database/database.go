@@ -2,22 +2,32 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"school-website/internal/markup"
 	"school-website/internal/models"
+	"school-website/internal/slug"
+	"school-website/internal/storage"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
-	db *sql.DB
+	db    *sql.DB
+	files storage.Storage // uploaded files of news and documents, removed together with their records
+	fts   bool            // the documents_fts full-text index exists, see setupDocumentSearch
+
+	uploadDir string // directory older document records store disk paths under
 }
 
-func New(filepath string) (*Database, error) {
+// New opens the database and migrates it. uploadDir is where uploads were
+// kept on disk before the storage backends, old document paths start with it.
+func New(filepath string, files storage.Storage, uploadDir string) (*Database, error) {
 	db, err := sql.Open("sqlite3", filepath)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %v", err)
@@ -27,11 +37,12 @@ func New(filepath string) (*Database, error) {
 		return nil, fmt.Errorf("error connecting to database: %v", err)
 	}
 
-	database := &Database{db: db}
+	database := &Database{db: db, files: files, uploadDir: uploadDir}
 
 	if err := database.createTables(); err != nil {
 		return nil, err
 	}
+	go database.backfillDocumentTexts()
 
 	return database, nil
 }
@@ -52,16 +63,25 @@ func (d *Database) createTables() error {
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             title TEXT NOT NULL,
             content TEXT NOT NULL,
+            content_html TEXT,
             image_url TEXT,
+            image_variants TEXT,
+            status TEXT NOT NULL DEFAULT 'published',
+            publish_at DATETIME,
+            search_text TEXT NOT NULL DEFAULT '',
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
         )`,
 
 		// Таблица папок
 		`CREATE TABLE IF NOT EXISTS folders (
             id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT NOT NULL UNIQUE,
+            parent_id INTEGER REFERENCES folders(id),
+            name TEXT NOT NULL,
             description TEXT,
             icon TEXT DEFAULT 'folder',
+            sort_order INTEGER NOT NULL DEFAULT 0,
+            visibility TEXT NOT NULL DEFAULT 'public',
+            search_text TEXT NOT NULL DEFAULT '',
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
         )`,
 
@@ -71,15 +91,137 @@ func (d *Database) createTables() error {
             title TEXT NOT NULL,
             description TEXT,
             file_name TEXT NOT NULL,
+            original_name TEXT,
             file_path TEXT NOT NULL,
             file_size INTEGER NOT NULL,
             file_type TEXT NOT NULL,
             category TEXT,
             folder_id INTEGER,
+            version INTEGER NOT NULL DEFAULT 1,
+            version_note TEXT,
+            visibility TEXT NOT NULL DEFAULT 'public',
+            search_text TEXT NOT NULL DEFAULT '',
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
             updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
             FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
         )`,
+
+		// Таблица пользователей админ-панели
+		`CREATE TABLE IF NOT EXISTS users (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+            full_name TEXT,
+            role TEXT NOT NULL DEFAULT 'administrator',
+            password_hash TEXT NOT NULL,
+            active INTEGER NOT NULL DEFAULT 1,
+            last_login_at DATETIME,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+
+		// Журнал изменений, сделанных в админ-панели
+		`CREATE TABLE IF NOT EXISTS audit_log (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            username TEXT NOT NULL,
+            action TEXT NOT NULL,
+            entity_type TEXT NOT NULL,
+            entity_id TEXT NOT NULL,
+            before_data TEXT,
+            after_data TEXT,
+            ip TEXT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at)`,
+
+		// История изменений новостей: полный снимок статьи на каждое изменение
+		`CREATE TABLE IF NOT EXISTS news_revisions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            news_id INTEGER NOT NULL,
+            number INTEGER NOT NULL,
+            title TEXT NOT NULL,
+            slug TEXT,
+            content TEXT NOT NULL,
+            image_url TEXT,
+            image_variants TEXT,
+            status TEXT NOT NULL,
+            publish_at DATETIME,
+            action TEXT NOT NULL,
+            restored_from INTEGER,
+            user_id INTEGER NOT NULL DEFAULT 0,
+            username TEXT NOT NULL DEFAULT '',
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE (news_id, number)
+        )`,
+
+		// Теги новостей (многие ко многим)
+		// name_key — имя в нижнем регистре: NOCASE в SQLite не работает для кириллицы
+		`CREATE TABLE IF NOT EXISTS tags (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL,
+            name_key TEXT NOT NULL UNIQUE,
+            slug TEXT NOT NULL UNIQUE,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE TABLE IF NOT EXISTS news_tags (
+            news_id INTEGER NOT NULL,
+            tag_id INTEGER NOT NULL,
+            PRIMARY KEY (news_id, tag_id)
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_news_tags_tag ON news_tags(tag_id)`,
+
+		// Фотогалереи новостей
+		`CREATE TABLE IF NOT EXISTS news_images (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            news_id INTEGER NOT NULL,
+            url TEXT NOT NULL,
+            variants TEXT,
+            caption TEXT NOT NULL DEFAULT '',
+            position INTEGER NOT NULL DEFAULT 0,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_news_images_news ON news_images(news_id, position)`,
+
+		// Версии документов: все загруженные файлы документа, текущий — с наибольшим номером
+		`CREATE TABLE IF NOT EXISTS document_versions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            document_id INTEGER NOT NULL,
+            version INTEGER NOT NULL,
+            file_name TEXT NOT NULL,
+            original_name TEXT NOT NULL,
+            file_path TEXT NOT NULL,
+            file_size INTEGER NOT NULL,
+            file_type TEXT NOT NULL,
+            note TEXT NOT NULL DEFAULT '',
+            user_id INTEGER,
+            username TEXT NOT NULL DEFAULT '',
+            created_at DATETIME NOT NULL,
+            UNIQUE(document_id, version)
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_document_versions_document ON document_versions(document_id, version)`,
+
+		// Текст текущего файла документа для поиска; пустой, если извлечь текст не удалось
+		`CREATE TABLE IF NOT EXISTS document_texts (
+            document_id INTEGER PRIMARY KEY,
+            content TEXT NOT NULL
+        )`,
+
+		// Ссылки для скачивания документа без входа; nonce входит в подписанные данные ссылки
+		`CREATE TABLE IF NOT EXISTS document_shares (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            document_id INTEGER NOT NULL,
+            nonce TEXT NOT NULL,
+            note TEXT NOT NULL DEFAULT '',
+            expires_at DATETIME NOT NULL,
+            max_downloads INTEGER NOT NULL DEFAULT 0,
+            downloads INTEGER NOT NULL DEFAULT 0,
+            user_id INTEGER,
+            username TEXT NOT NULL DEFAULT '',
+            revoked_at DATETIME,
+            created_at DATETIME NOT NULL
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_document_shares_document ON document_shares(document_id)`,
 	}
 
 	for _, query := range queries {
@@ -108,10 +250,199 @@ func (d *Database) migrateTables() error {
 		return err
 	}
 
-	// Проверяем и добавляем updated_at в таблицу documents
-	if err := d.addColumnIfNotExists("documents", "updated_at", "DATETIME DEFAULT CURRENT_TIMESTAMP"); err != nil {
+	// Проверяем и добавляем updated_at в таблицу documents. SQLite не позволяет
+	// добавить колонку со значением по умолчанию CURRENT_TIMESTAMP, поэтому
+	// у старых документов дата изменения заполняется датой создания.
+	if err := d.addColumnIfNotExists("documents", "updated_at", "DATETIME"); err != nil {
+		return err
+	}
+	if _, err := d.db.Exec(`UPDATE documents SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE updated_at IS NULL`); err != nil {
+		return fmt.Errorf("error backfilling documents updated_at: %v", err)
+	}
+
+	// Проверяем и добавляем role в таблицу users (существующие пользователи становятся администраторами)
+	if err := d.addColumnIfNotExists("users", "role", "TEXT NOT NULL DEFAULT 'administrator'"); err != nil {
+		return err
+	}
+
+	// Проверяем и добавляем status и publish_at в таблицу news
+	if err := d.addColumnIfNotExists("news", "status", "TEXT NOT NULL DEFAULT 'published'"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfNotExists("news", "publish_at", "DATETIME"); err != nil {
+		return err
+	}
+	// Уже опубликованные новости считаются опубликованными в момент создания
+	if _, err := d.db.Exec(`UPDATE news SET publish_at = created_at WHERE publish_at IS NULL AND status = 'published'`); err != nil {
+		return fmt.Errorf("error backfilling news publish_at: %v", err)
+	}
+
+	// Проверяем и добавляем slug в таблицу news, заполняем его для старых новостей
+	if err := d.addColumnIfNotExists("news", "slug", "TEXT"); err != nil {
+		return err
+	}
+	if err := d.backfillNewsSlugs(); err != nil {
+		return err
+	}
+	if _, err := d.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_news_slug ON news(slug)`); err != nil {
+		return fmt.Errorf("error creating news slug index: %v", err)
+	}
+
+	// Проверяем и добавляем content_html в таблицу news, отрисовываем HTML для старых новостей
+	if err := d.addColumnIfNotExists("news", "content_html", "TEXT"); err != nil {
+		return err
+	}
+	if err := d.backfillNewsHTML(); err != nil {
+		return err
+	}
+
+	// Размеры изображений (JSON со списком вариантов)
+	if err := d.addColumnIfNotExists("news", "image_variants", "TEXT"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfNotExists("news_revisions", "image_variants", "TEXT"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfNotExists("news_images", "variants", "TEXT"); err != nil {
+		return err
+	}
+
+	// Для новостей без истории сохраняем текущее состояние как первую ревизию
+	if err := d.backfillNewsRevisions(); err != nil {
+		return err
+	}
+
+	// Исходное имя документа (файл хранится под случайным именем)
+	if err := d.addColumnIfNotExists("documents", "original_name", "TEXT"); err != nil {
+		return err
+	}
+	// Старые записи хранят путь к файлу на диске, новые — ключ в хранилище
+	if err := d.convertDocumentPaths(); err != nil {
+		return fmt.Errorf("error converting document paths to storage keys: %v", err)
+	}
+	// Переименование файлов, загруженных до появления случайных имен
+	if err := d.renameStoredFiles(); err != nil {
+		return err
+	}
+
+	// Номер и комментарий текущей версии документа
+	if err := d.addColumnIfNotExists("documents", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfNotExists("documents", "version_note", "TEXT"); err != nil {
+		return err
+	}
+	// Для документов без истории сохраняем текущий файл как первую версию
+	if err := d.backfillDocumentVersions(); err != nil {
+		return err
+	}
+
+	// Вложенные папки: имя папки уникально только среди папок одного родителя
+	if err := d.migrateFolderTree(); err != nil {
+		return err
+	}
+	// Явный порядок папок; у существующих папок 0, они остаются в алфавитном порядке
+	if err := d.addColumnIfNotExists("folders", "sort_order", "INTEGER NOT NULL DEFAULT 0"); err != nil {
 		return err
 	}
+	if _, err := d.db.Exec(`CREATE INDEX IF NOT EXISTS idx_documents_folder ON documents(folder_id)`); err != nil {
+		return fmt.Errorf("error creating documents folder index: %v", err)
+	}
+
+	// Видимость документов и папок; существующие остаются общедоступными
+	if err := d.addColumnIfNotExists("documents", "visibility", "TEXT NOT NULL DEFAULT 'public'"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfNotExists("folders", "visibility", "TEXT NOT NULL DEFAULT 'public'"); err != nil {
+		return err
+	}
+
+	// Текст для поиска по сайту (в нижнем регистре, ё заменена на е)
+	for _, table := range []string{"news", "documents", "folders"} {
+		if err := d.addColumnIfNotExists(table, "search_text", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	if err := d.backfillSearchText(); err != nil {
+		return err
+	}
+
+	// Полнотекстовый поиск по названию, описанию и тексту документов
+	if err := d.setupDocumentSearch(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// backfillNewsSlugs генерирует slug для новостей, у которых его еще нет
+func (d *Database) backfillNewsSlugs() error {
+	rows, err := d.db.Query(`SELECT id, title FROM news WHERE slug IS NULL OR slug = '' ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("error reading news without slug: %v", err)
+	}
+
+	type pending struct {
+		id    int
+		title string
+	}
+	var items []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news without slug: %v", err)
+		}
+		items = append(items, p)
+	}
+	rows.Close()
+
+	for _, item := range items {
+		newsSlug, err := d.UniqueNewsSlug(item.title, item.id)
+		if err != nil {
+			return err
+		}
+		if _, err := d.db.Exec(`UPDATE news SET slug = ? WHERE id = ?`, newsSlug, item.id); err != nil {
+			return fmt.Errorf("error setting slug for news %d: %v", item.id, err)
+		}
+		log.Printf("Generated slug %s for news %d", newsSlug, item.id)
+	}
+
+	return nil
+}
+
+// backfillNewsHTML отрисовывает content_html для новостей, у которых его еще нет
+func (d *Database) backfillNewsHTML() error {
+	rows, err := d.db.Query(`SELECT id, content FROM news WHERE content_html IS NULL ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("error reading news without html: %v", err)
+	}
+
+	rendered := map[int]string{}
+	for rows.Next() {
+		var id int
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news without html: %v", err)
+		}
+		html, err := markup.Render(content)
+		if err != nil {
+			log.Printf("Warning: failed to render content of news %d: %v", id, err)
+			continue
+		}
+		rendered[id] = html
+	}
+	rows.Close()
+
+	for id, html := range rendered {
+		if _, err := d.db.Exec(`UPDATE news SET content_html = ? WHERE id = ?`, html, id); err != nil {
+			return fmt.Errorf("error setting html for news %d: %v", id, err)
+		}
+	}
+	if len(rendered) > 0 {
+		log.Printf("Rendered HTML content for %d news articles", len(rendered))
+	}
 
 	return nil
 }
@@ -142,7 +473,19 @@ func (d *Database) addColumnIfNotExists(tableName, columnName, columnDef string)
 	return nil
 }
 
+// createDefaultFolders создает стандартные папки в новой базе. Папки создаются
+// только один раз, когда папок еще нет, чтобы переименованные или удаленные
+// стандартные папки не появлялись снова при перезапуске.
 func (d *Database) createDefaultFolders() {
+	var count int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM folders`).Scan(&count); err != nil {
+		log.Printf("Warning: failed to count folders: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
 	defaultFolders := []struct {
 		name        string
 		description string
@@ -156,10 +499,10 @@ func (d *Database) createDefaultFolders() {
 		{"Прочее", "Другие документы", "folder"},
 	}
 
-	for _, folder := range defaultFolders {
+	for i, folder := range defaultFolders {
 		_, err := d.db.Exec(
-			`INSERT OR IGNORE INTO folders (name, description, icon) VALUES (?, ?, ?)`,
-			folder.name, folder.description, folder.icon,
+			`INSERT OR IGNORE INTO folders (name, description, icon, sort_order) VALUES (?, ?, ?, ?)`,
+			folder.name, folder.description, folder.icon, i+1,
 		)
 		if err != nil {
 			log.Printf("Warning: failed to create default folder %s: %v", folder.name, err)
@@ -220,73 +563,288 @@ func (d *Database) GetContacts() ([]models.ContactEntry, error) {
 
 // --- News Operations ---
 
-func (d *Database) SaveNews(title, content, imageURL string) error {
-	insertSQL := `INSERT INTO news(title, content, image_url, created_at) VALUES (?, ?, ?, ?)`
+const newsColumns = `n.id, COALESCE(n.slug, '') as slug, n.title, n.content, COALESCE(n.content_html, '') as content_html,
+			  COALESCE(n.image_url, '') as image_url, COALESCE(n.image_variants, '') as image_variants,
+			  n.status, n.publish_at, n.created_at`
+
+// publicNewsCondition selects articles that are published (or scheduled) and already due
+const publicNewsCondition = `n.status IN ('published', 'scheduled')
+			  AND (n.publish_at IS NULL OR datetime(n.publish_at) <= datetime('now'))`
+
+func scanNews(scanner interface{ Scan(...interface{}) error }) (models.NewsArticle, error) {
+	var a models.NewsArticle
+	var publishAt sql.NullTime
+	var variants string
+	err := scanner.Scan(&a.ID, &a.Slug, &a.Title, &a.Content, &a.ContentHTML, &a.ImageURL, &variants,
+		&a.Status, &publishAt, &a.CreatedAt)
+	a.ImageVariants = decodeVariants(variants)
+	if publishAt.Valid {
+		a.PublishAt = &publishAt.Time
+	}
+	if a.Slug != "" {
+		a.URL = "/news/" + a.Slug
+	}
+	// A scheduled article whose time has come is reported as published
+	if a.Status == models.NewsScheduled && a.IsPublic(time.Now()) {
+		a.Status = models.NewsPublished
+	}
+	return a, err
+}
+
+// encodeVariants stores image variants as JSON (NULL when there are none)
+func encodeVariants(variants []models.ImageVariant) interface{} {
+	if len(variants) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(variants)
+	if err != nil {
+		log.Printf("Warning: failed to encode image variants: %v", err)
+		return nil
+	}
+	return string(data)
+}
+
+func decodeVariants(data string) []models.ImageVariant {
+	if data == "" {
+		return nil
+	}
+	var variants []models.ImageVariant
+	if err := json.Unmarshal([]byte(data), &variants); err != nil {
+		log.Printf("Warning: failed to decode image variants: %v", err)
+		return nil
+	}
+	return variants
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// NewsSlugTaken reports whether another article (not excludeID) already uses the slug
+func (d *Database) NewsSlugTaken(newsSlug string, excludeID int) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM news WHERE slug = ? AND id != ?`, newsSlug, excludeID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking news slug %s: %v", newsSlug, err)
+	}
+	return count > 0, nil
+}
+
+// UniqueNewsSlug generates a slug from the title that no other article
+// uses, adding a numeric suffix ("-2", "-3", ...) when needed.
+func (d *Database) UniqueNewsSlug(title string, excludeID int) (string, error) {
+	base := slug.Make(title)
+	if base == "" {
+		base = "news"
+	}
+	// Purely numeric slugs would be mistaken for article IDs
+	if _, err := strconv.Atoi(base); err == nil {
+		base = "news-" + base
+	}
+
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := d.NewsSlugTaken(candidate, excludeID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (d *Database) SaveNews(article models.NewsArticle) (int64, error) {
+	if article.Slug == "" {
+		newsSlug, err := d.UniqueNewsSlug(article.Title, 0)
+		if err != nil {
+			return 0, err
+		}
+		article.Slug = newsSlug
+	}
+
+	insertSQL := `INSERT INTO news(slug, title, content, content_html, image_url, image_variants, status, publish_at, search_text, created_at)
+                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	statement, err := d.db.Prepare(insertSQL)
 	if err != nil {
-		return fmt.Errorf("error preparing SaveNews statement: %v", err)
+		return 0, fmt.Errorf("error preparing SaveNews statement: %v", err)
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(title, content, imageURL, time.Now())
+	result, err := statement.Exec(article.Slug, article.Title, article.Content, article.ContentHTML, article.ImageURL,
+		encodeVariants(article.ImageVariants), article.Status, nullableTime(article.PublishAt), newsSearchText(article), time.Now())
 	if err != nil {
-		return fmt.Errorf("error saving news: %v", err)
+		return 0, fmt.Errorf("error saving news: %v", err)
 	}
 
-	log.Printf("News successfully saved: %s", title)
-	return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	log.Printf("News successfully saved: %s (ID: %d, status: %s)", article.Title, id, article.Status)
+	return id, nil
 }
 
-func (d *Database) GetNews() ([]models.NewsArticle, error) {
-	query := `SELECT id, title, content, COALESCE(image_url, '') as image_url, created_at 
-			  FROM news ORDER BY created_at DESC`
+const newsExcerptLength = 200
 
-	rows, err := d.db.Query(query)
+var newsSortOrders = map[string]string{
+	models.NewsSortNewest: "COALESCE(n.publish_at, n.created_at) DESC, n.id DESC",
+	models.NewsSortOldest: "COALESCE(n.publish_at, n.created_at) ASC, n.id ASC",
+	models.NewsSortTitle:  "n.title COLLATE NOCASE ASC, n.id ASC",
+}
+
+// GetNews returns a page of news articles matching the filter together with
+// the total number of matching articles.
+func (d *Database) GetNews(filter models.NewsFilter) ([]models.NewsArticle, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.PublicOnly {
+		conditions = append(conditions, publicNewsCondition)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		conditions = append(conditions, `(n.title LIKE ? ESCAPE '\' OR n.content LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, `n.id IN (SELECT nt.news_id FROM news_tags nt
+			  JOIN tags t ON t.id = nt.tag_id WHERE t.slug = ?)`)
+		args = append(args, filter.Tag)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "datetime(COALESCE(n.publish_at, n.created_at)) >= datetime(?)")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "datetime(COALESCE(n.publish_at, n.created_at)) < datetime(?)")
+		args = append(args, filter.To.UTC())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM news n"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting news: %v", err)
+	}
+
+	orderBy, ok := newsSortOrders[filter.Sort]
+	if !ok {
+		orderBy = newsSortOrders[models.NewsSortNewest]
+	}
+
+	columns := newsColumns
+	if filter.ExcerptOnly {
+		// Only read the beginning of the content; it is trimmed to an excerpt below.
+		// The HTML needs more room because of the markup.
+		columns = strings.Replace(columns, "n.content,", fmt.Sprintf("substr(n.content, 1, %d),", newsExcerptLength*2), 1)
+		columns = strings.Replace(columns, "COALESCE(n.content_html, '')",
+			fmt.Sprintf("substr(COALESCE(n.content_html, ''), 1, %d)", newsExcerptLength*8), 1)
+	}
+
+	query := `SELECT ` + columns + ` FROM news n` + where + ` ORDER BY ` + orderBy
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("GetNews query failed: %v", err)
+		return nil, 0, fmt.Errorf("GetNews query failed: %v", err)
 	}
 	defer rows.Close()
 
-	var articles []models.NewsArticle
+	articles := []models.NewsArticle{}
 	for rows.Next() {
-		var a models.NewsArticle
-		if err := rows.Scan(&a.ID, &a.Title, &a.Content, &a.ImageURL, &a.CreatedAt); err != nil {
+		a, err := scanNews(rows)
+		if err != nil {
 			log.Printf("Error scanning news: %v", err)
 			continue
 		}
+		if filter.ExcerptOnly {
+			text := a.Content
+			if a.ContentHTML != "" {
+				text = markup.PlainText(a.ContentHTML)
+			}
+			a.Excerpt = markup.Excerpt(text, newsExcerptLength)
+			a.Content, a.ContentHTML = "", ""
+		}
 		articles = append(articles, a)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating news: %v", err)
+		return nil, 0, fmt.Errorf("error iterating news: %v", err)
+	}
+
+	if err := d.attachNewsTags(articles); err != nil {
+		return nil, 0, err
 	}
 
-	log.Printf("Retrieved %d news articles from database", len(articles))
-	return articles, nil
+	log.Printf("Retrieved %d of %d news articles from database", len(articles), total)
+	return articles, total, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally (with ESCAPE '\')
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func (d *Database) GetNewsArticle(id string) (models.NewsArticle, error) {
-	var a models.NewsArticle
-	query := `SELECT id, title, content, COALESCE(image_url, '') as image_url, created_at 
-			  FROM news WHERE id = ?`
+	query := `SELECT ` + newsColumns + ` FROM news n WHERE n.id = ?`
 
-	err := d.db.QueryRow(query, id).Scan(&a.ID, &a.Title, &a.Content, &a.ImageURL, &a.CreatedAt)
+	a, err := scanNews(d.db.QueryRow(query, id))
 	if err != nil {
 		return a, fmt.Errorf("error getting news with ID %s: %v", id, err)
 	}
 
+	if a.Tags, err = d.GetNewsTags(a.ID); err != nil {
+		return a, err
+	}
+	if a.Images, err = d.GetNewsImages(a.ID); err != nil {
+		return a, err
+	}
+
 	return a, nil
 }
 
-func (d *Database) UpdateNewsArticle(id, title, content, imageURL string) error {
-	updateSQL := `UPDATE news SET title = ?, content = ?, image_url = ? WHERE id = ?`
+func (d *Database) GetNewsArticleBySlug(newsSlug string) (models.NewsArticle, error) {
+	query := `SELECT ` + newsColumns + ` FROM news n WHERE n.slug = ?`
+
+	a, err := scanNews(d.db.QueryRow(query, newsSlug))
+	if err != nil {
+		return a, fmt.Errorf("error getting news with slug %s: %v", newsSlug, err)
+	}
+
+	if a.Tags, err = d.GetNewsTags(a.ID); err != nil {
+		return a, err
+	}
+	if a.Images, err = d.GetNewsImages(a.ID); err != nil {
+		return a, err
+	}
+
+	return a, nil
+}
+
+// UpdateNewsArticle overwrites the article. An empty Slug keeps the current one.
+func (d *Database) UpdateNewsArticle(id string, article models.NewsArticle) error {
+	updateSQL := `UPDATE news SET title = ?, content = ?, content_html = ?, image_url = ?, image_variants = ?, status = ?, publish_at = ?,
+                  search_text = ?, slug = COALESCE(NULLIF(?, ''), slug) WHERE id = ?`
 	statement, err := d.db.Prepare(updateSQL)
 	if err != nil {
 		return fmt.Errorf("error preparing UpdateNewsArticle statement: %v", err)
 	}
 	defer statement.Close()
 
-	result, err := statement.Exec(title, content, imageURL, id)
+	result, err := statement.Exec(article.Title, article.Content, article.ContentHTML, article.ImageURL,
+		encodeVariants(article.ImageVariants), article.Status, nullableTime(article.PublishAt), newsSearchText(article), article.Slug, id)
 	if err != nil {
 		return fmt.Errorf("error updating news with ID %s: %v", id, err)
 	}
@@ -297,11 +855,30 @@ func (d *Database) UpdateNewsArticle(id, title, content, imageURL string) error
 	return nil
 }
 
+func (d *Database) SetNewsStatus(id, status string, publishAt *time.Time) error {
+	result, err := d.db.Exec(`UPDATE news SET status = ?, publish_at = ? WHERE id = ?`, status, nullableTime(publishAt), id)
+	if err != nil {
+		return fmt.Errorf("error updating status of news with ID %s: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("news with ID %s not found", id)
+	}
+
+	log.Printf("News with ID %s status=%s", id, status)
+	return nil
+}
+
 func (d *Database) DeleteNewsArticle(id string) error {
 	log.Printf("Deleting news with ID: %s", id)
 
-	var imageURL string
-	err := d.db.QueryRow("SELECT COALESCE(image_url, '') FROM news WHERE id = ?", id).Scan(&imageURL)
+	var imageURL, variants string
+	err := d.db.QueryRow("SELECT COALESCE(image_url, ''), COALESCE(image_variants, '') FROM news WHERE id = ?", id).
+		Scan(&imageURL, &variants)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("news with ID %s not found", id)
@@ -309,13 +886,12 @@ func (d *Database) DeleteNewsArticle(id string) error {
 		return fmt.Errorf("error getting news info: %v", err)
 	}
 
-	if imageURL != "" && strings.HasPrefix(imageURL, "/uploads/") {
-		filePath := "public" + imageURL
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("Warning: failed to delete file %s: %v", filePath, err)
-		} else {
-			log.Printf("File %s successfully deleted", filePath)
-		}
+	for _, url := range models.ImageFiles(imageURL, decodeVariants(variants)) {
+		d.removeUpload(url)
+	}
+	revisionFiles, err := d.revisionImageFiles(id)
+	if err != nil {
+		log.Printf("Warning: %v", err)
 	}
 
 	deleteSQL := `DELETE FROM news WHERE id = ?`
@@ -333,15 +909,54 @@ func (d *Database) DeleteNewsArticle(id string) error {
 		return fmt.Errorf("news with ID %s not found", id)
 	}
 
+	if _, err := d.db.Exec(`DELETE FROM news_revisions WHERE news_id = ?`, id); err != nil {
+		log.Printf("Warning: failed to delete revisions of news %s: %v", id, err)
+	} else {
+		// Covers replaced by edits were kept only for the revisions
+		for _, url := range revisionFiles {
+			if !d.uploadInUse(url) {
+				d.removeUpload(url)
+			}
+		}
+	}
+	if _, err := d.db.Exec(`DELETE FROM news_tags WHERE news_id = ?`, id); err != nil {
+		log.Printf("Warning: failed to delete tags of news %s: %v", id, err)
+	}
+
+	// Фотографии галереи удаляются вместе с новостью
+	if galleryURLs, err := d.deleteNewsImages(id); err != nil {
+		log.Printf("Warning: failed to delete gallery of news %s: %v", id, err)
+	} else {
+		for _, url := range galleryURLs {
+			d.removeUpload(url)
+		}
+	}
+
 	log.Printf("News with ID %s successfully deleted", id)
 	return nil
 }
 
 // --- Document Operations ---
 
+// documentColumns selects a document joined with its folder (alias f); the
+// last column tells whether visitors may see it, see publicDocument
+const documentColumns = `d.id, d.title, COALESCE(d.description, ''), d.file_name, COALESCE(d.original_name, d.file_name), d.file_path,
+			  d.file_size, d.file_type, d.version, COALESCE(d.version_note, ''), COALESCE(d.category, ''),
+			  COALESCE(d.folder_id, 0), COALESCE(f.name, ''), d.created_at, d.updated_at, d.visibility, ` + publicDocument
+
+// scanDocument reads a row selected with documentColumns followed by the extra columns
+func scanDocument(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Document, error) {
+	var doc models.Document
+	dest := []interface{}{&doc.ID, &doc.Title, &doc.Description, &doc.FileName, &doc.OriginalName, &doc.FilePath,
+		&doc.FileSize, &doc.FileType, &doc.Version, &doc.VersionNote, &doc.Category,
+		&doc.FolderID, &doc.FolderName, &doc.CreatedAt, &doc.UpdatedAt, &doc.Visibility, &doc.Public}
+	err := scanner.Scan(append(dest, extra...)...)
+	return doc, err
+}
+
 func (d *Database) SaveDocument(doc models.Document) (int64, error) {
-	insertSQL := `INSERT INTO documents(title, description, file_name, file_path, file_size, file_type, category, folder_id, created_at, updated_at) 
-                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertSQL := `INSERT INTO documents(title, description, file_name, original_name, file_path, file_size, file_type, category, folder_id, visibility, search_text, created_at, updated_at) 
+                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	statement, err := d.db.Prepare(insertSQL)
 	if err != nil {
@@ -353,11 +968,14 @@ func (d *Database) SaveDocument(doc models.Document) (int64, error) {
 		doc.Title,
 		doc.Description,
 		doc.FileName,
+		doc.OriginalName,
 		doc.FilePath,
 		doc.FileSize,
 		doc.FileType,
 		doc.Category,
 		doc.FolderID, // Добавлено
+		doc.Visibility,
+		searchText(doc.Title, doc.Description),
 		time.Now(),
 		time.Now(),
 	)
@@ -370,19 +988,21 @@ func (d *Database) SaveDocument(doc models.Document) (int64, error) {
 		return 0, fmt.Errorf("error getting last insert id: %v", err)
 	}
 
+	if err := d.indexDocument(int(id)); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	log.Printf("Document successfully saved: %s (ID: %d)", doc.Title, id)
 	return id, nil
 }
 
-func (d *Database) GetDocuments() ([]models.Document, error) {
-	query := `SELECT d.id, d.title, COALESCE(d.description, '') as description, 
-              d.file_name, d.file_path, d.file_size, d.file_type, 
-              COALESCE(d.category, '') as category, 
-              COALESCE(d.folder_id, 0) as folder_id,
-              COALESCE(f.name, '') as folder_name,
-              d.created_at, d.updated_at
-              FROM documents d
-              LEFT JOIN folders f ON d.folder_id = f.id
+func (d *Database) GetDocuments(publicOnly bool) ([]models.Document, error) {
+	where := ""
+	if publicOnly {
+		where = ` WHERE ` + publicDocument
+	}
+	query := `SELECT ` + documentColumns + ` FROM documents d
+              LEFT JOIN folders f ON d.folder_id = f.id` + where + `
               ORDER BY d.created_at DESC`
 
 	rows, err := d.db.Query(query)
@@ -393,21 +1013,8 @@ func (d *Database) GetDocuments() ([]models.Document, error) {
 
 	var documents []models.Document
 	for rows.Next() {
-		var doc models.Document
-		if err := rows.Scan(
-			&doc.ID,
-			&doc.Title,
-			&doc.Description,
-			&doc.FileName,
-			&doc.FilePath,
-			&doc.FileSize,
-			&doc.FileType,
-			&doc.Category,
-			&doc.FolderID,
-			&doc.FolderName,
-			&doc.CreatedAt,
-			&doc.UpdatedAt,
-		); err != nil {
+		doc, err := scanDocument(rows)
+		if err != nil {
 			log.Printf("Error scanning document: %v", err)
 			continue
 		}
@@ -423,30 +1030,11 @@ func (d *Database) GetDocuments() ([]models.Document, error) {
 }
 
 func (d *Database) GetDocument(id string) (models.Document, error) {
-	var doc models.Document
-	query := `SELECT d.id, d.title, COALESCE(d.description, '') as description, d.file_name, d.file_path,
-			  d.file_size, d.file_type, COALESCE(d.category, '') as category, 
-			  COALESCE(d.folder_id, 0) as folder_id,
-			  COALESCE(f.name, '') as folder_name,
-			  d.created_at, d.updated_at
-			  FROM documents d
+	query := `SELECT ` + documentColumns + ` FROM documents d
 			  LEFT JOIN folders f ON d.folder_id = f.id
 			  WHERE d.id = ?`
 
-	err := d.db.QueryRow(query, id).Scan(
-		&doc.ID,
-		&doc.Title,
-		&doc.Description,
-		&doc.FileName,
-		&doc.FilePath,
-		&doc.FileSize,
-		&doc.FileType,
-		&doc.Category,
-		&doc.FolderID,
-		&doc.FolderName,
-		&doc.CreatedAt,
-		&doc.UpdatedAt,
-	)
+	doc, err := scanDocument(d.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return doc, fmt.Errorf("document with ID %s not found", id)
@@ -457,15 +1045,14 @@ func (d *Database) GetDocument(id string) (models.Document, error) {
 	return doc, nil
 }
 
-func (d *Database) GetDocumentsByCategory(category string) ([]models.Document, error) {
-	query := `SELECT d.id, d.title, COALESCE(d.description, '') as description, d.file_name, d.file_path,
-			  d.file_size, d.file_type, COALESCE(d.category, '') as category, 
-			  COALESCE(d.folder_id, 0) as folder_id,
-			  COALESCE(f.name, '') as folder_name,
-			  d.created_at, d.updated_at
-			  FROM documents d
+func (d *Database) GetDocumentsByCategory(category string, publicOnly bool) ([]models.Document, error) {
+	where := ""
+	if publicOnly {
+		where = ` AND ` + publicDocument
+	}
+	query := `SELECT ` + documentColumns + ` FROM documents d
 			  LEFT JOIN folders f ON d.folder_id = f.id
-			  WHERE d.category = ? ORDER BY d.created_at DESC`
+			  WHERE d.category = ?` + where + ` ORDER BY d.created_at DESC`
 
 	rows, err := d.db.Query(query, category)
 	if err != nil {
@@ -475,21 +1062,8 @@ func (d *Database) GetDocumentsByCategory(category string) ([]models.Document, e
 
 	var documents []models.Document
 	for rows.Next() {
-		var doc models.Document
-		if err := rows.Scan(
-			&doc.ID,
-			&doc.Title,
-			&doc.Description,
-			&doc.FileName,
-			&doc.FilePath,
-			&doc.FileSize,
-			&doc.FileType,
-			&doc.Category,
-			&doc.FolderID,
-			&doc.FolderName,
-			&doc.CreatedAt,
-			&doc.UpdatedAt,
-		); err != nil {
+		doc, err := scanDocument(rows)
+		if err != nil {
 			log.Printf("Error scanning document: %v", err)
 			continue
 		}
@@ -504,6 +1078,69 @@ func (d *Database) GetDocumentsByCategory(category string) ([]models.Document, e
 	return documents, nil
 }
 
+// UpdateDocument saves the title, description, category, folder and
+// visibility of a document and sets its modification time. A zero FolderID
+// moves the document out of any folder.
+func (d *Database) UpdateDocument(doc models.Document) error {
+	updateSQL := `UPDATE documents SET title = ?, description = ?, category = ?, folder_id = NULLIF(?, 0), visibility = ?,
+                  search_text = ?, updated_at = ? WHERE id = ?`
+
+	result, err := d.db.Exec(updateSQL, doc.Title, doc.Description, doc.Category, doc.FolderID, doc.Visibility,
+		searchText(doc.Title, doc.Description), time.Now(), doc.ID)
+	if err != nil {
+		return fmt.Errorf("error updating document: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("document with ID %d not found", doc.ID)
+	}
+	if err := d.indexDocument(doc.ID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	log.Printf("Document with ID %d successfully updated", doc.ID)
+	return nil
+}
+
+// MoveDocuments puts several documents into a folder (zero: out of any
+// folder). Either all documents are moved or, if one of them doesn't exist,
+// none; the error then names the missing document.
+func (d *Database) MoveDocuments(ids []int, folderID int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare(`UPDATE documents SET folder_id = NULLIF(?, 0), updated_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("error preparing MoveDocuments statement: %v", err)
+	}
+	defer statement.Close()
+
+	now := time.Now()
+	for _, id := range ids {
+		result, err := statement.Exec(folderID, now, id)
+		if err != nil {
+			return fmt.Errorf("error moving document %d: %v", id, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("document with ID %d not found", id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing document move: %v", err)
+	}
+
+	log.Printf("Moved %d documents to folder %d", len(ids), folderID)
+	return nil
+}
+
 func (d *Database) DeleteDocument(id string) error {
 	log.Printf("Deleting document with ID: %s", id)
 
@@ -517,13 +1154,10 @@ func (d *Database) DeleteDocument(id string) error {
 		return fmt.Errorf("error getting document info: %v", err)
 	}
 
-	// Delete the file from filesystem
-	if filePath != "" {
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("Warning: failed to delete file %s: %v", filePath, err)
-		} else {
-			log.Printf("File %s successfully deleted", filePath)
-		}
+	// Files of all versions, the current one included
+	filePaths, err := d.documentFiles(id)
+	if err != nil {
+		return fmt.Errorf("error getting document versions: %v", err)
 	}
 
 	// Delete from database
@@ -532,6 +1166,25 @@ func (d *Database) DeleteDocument(id string) error {
 	if err != nil {
 		return fmt.Errorf("error deleting document: %v", err)
 	}
+	if _, err := d.db.Exec(`DELETE FROM document_versions WHERE document_id = ?`, id); err != nil {
+		log.Printf("Warning: failed to delete versions of document %s: %v", id, err)
+	}
+	if _, err := d.db.Exec(`DELETE FROM document_shares WHERE document_id = ?`, id); err != nil {
+		log.Printf("Warning: failed to delete share links of document %s: %v", id, err)
+	}
+	d.unindexDocument(id)
+
+	// Delete the files from storage
+	for _, path := range filePaths {
+		if path == "" {
+			continue
+		}
+		if err := d.files.Delete(path); err != nil {
+			log.Printf("Warning: failed to delete file %s: %v", path, err)
+		} else {
+			log.Printf("File %s successfully deleted", path)
+		}
+	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -548,10 +1201,38 @@ func (d *Database) DeleteDocument(id string) error {
 
 // --- Folder Operations ---
 
-func (d *Database) GetFolders() ([]models.Folder, error) {
-	query := `SELECT id, name, COALESCE(description, '') as description, 
-			  COALESCE(icon, 'folder') as icon, created_at 
-			  FROM folders ORDER BY name ASC`
+// folderColumns selects a folder with the number and total size of the
+// documents directly inside it, only the public ones with publicOnly. The
+// last but one column tells whether visitors may see the folder, see
+// publicFolder.
+func folderColumns(publicOnly bool) string {
+	documents := `documents d WHERE d.folder_id = folders.id`
+	if publicOnly {
+		documents += ` AND ` + publicDocument
+	}
+	return `id, COALESCE(parent_id, 0), name, COALESCE(description, ''), COALESCE(icon, 'folder'), sort_order,
+			  (SELECT COUNT(*) FROM ` + documents + `),
+			  (SELECT COALESCE(SUM(d.file_size), 0) FROM ` + documents + `),
+			  visibility, ` + publicFolder + `, created_at`
+}
+
+// folderOrder sorts folders by their explicit position, then alphabetically
+const folderOrder = `ORDER BY sort_order ASC, name ASC`
+
+func scanFolder(scanner interface{ Scan(...interface{}) error }) (models.Folder, error) {
+	var folder models.Folder
+	err := scanner.Scan(&folder.ID, &folder.ParentID, &folder.Name, &folder.Description, &folder.Icon, &folder.SortOrder,
+		&folder.DocumentCount, &folder.TotalSize, &folder.Visibility, &folder.Public, &folder.CreatedAt)
+	return folder, err
+}
+
+// GetFolders lists all folders or, with publicOnly, the ones visitors may see
+func (d *Database) GetFolders(publicOnly bool) ([]models.Folder, error) {
+	where := ""
+	if publicOnly {
+		where = `WHERE ` + publicFolder + ` `
+	}
+	query := `SELECT ` + folderColumns(publicOnly) + ` FROM folders ` + where + folderOrder
 
 	rows, err := d.db.Query(query)
 	if err != nil {
@@ -561,8 +1242,8 @@ func (d *Database) GetFolders() ([]models.Folder, error) {
 
 	var folders []models.Folder
 	for rows.Next() {
-		var folder models.Folder
-		if err := rows.Scan(&folder.ID, &folder.Name, &folder.Description, &folder.Icon, &folder.CreatedAt); err != nil {
+		folder, err := scanFolder(rows)
+		if err != nil {
 			log.Printf("Error scanning folder: %v", err)
 			continue
 		}
@@ -577,15 +1258,49 @@ func (d *Database) GetFolders() ([]models.Folder, error) {
 	return folders, nil
 }
 
-func (d *Database) CreateFolder(name, description, icon string) (int64, error) {
-	insertSQL := `INSERT INTO folders(name, description, icon, created_at) VALUES (?, ?, ?, ?)`
+func (d *Database) GetFolder(id string) (models.Folder, error) {
+	query := `SELECT ` + folderColumns(false) + ` FROM folders WHERE id = ?`
+
+	folder, err := scanFolder(d.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return folder, fmt.Errorf("folder with ID %s not found", id)
+		}
+		return folder, fmt.Errorf("error getting folder with ID %s: %v", id, err)
+	}
+
+	return folder, nil
+}
+
+// GetPublicFolder returns a folder visitors may see, counting only its
+// public documents. A staff-only folder is reported as not found.
+func (d *Database) GetPublicFolder(id string) (models.Folder, error) {
+	query := `SELECT ` + folderColumns(true) + ` FROM folders WHERE id = ? AND ` + publicFolder
+
+	folder, err := scanFolder(d.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return folder, fmt.Errorf("folder with ID %s not found", id)
+		}
+		return folder, fmt.Errorf("error getting folder with ID %s: %v", id, err)
+	}
+
+	return folder, nil
+}
+
+// CreateFolder creates a folder inside parentID, or at the top level if it
+// is zero. The new folder is placed after its siblings.
+func (d *Database) CreateFolder(name, description, icon, visibility string, parentID int) (int64, error) {
+	insertSQL := `INSERT INTO folders(parent_id, name, description, icon, visibility, search_text, sort_order, created_at)
+                  SELECT NULLIF(?1, 0), ?2, ?3, ?4, ?6, ?7, COALESCE(MAX(sort_order), 0) + 1, ?5
+                  FROM folders WHERE COALESCE(parent_id, 0) = ?1`
 	statement, err := d.db.Prepare(insertSQL)
 	if err != nil {
 		return 0, fmt.Errorf("error preparing CreateFolder statement: %v", err)
 	}
 	defer statement.Close()
 
-	result, err := statement.Exec(name, description, icon, time.Now())
+	result, err := statement.Exec(parentID, name, description, icon, time.Now(), visibility, searchText(name, description))
 	if err != nil {
 		return 0, fmt.Errorf("error creating folder: %v", err)
 	}
@@ -612,7 +1327,8 @@ func (d *Database) DeleteFolder(id string) error {
 		return fmt.Errorf("error getting folder info: %v", err)
 	}
 
-	// Delete the folder (documents will have folder_id set to NULL due to ON DELETE SET NULL)
+	// Delete the folder. Only empty folders get here, see CountFolderContents
+	// and DeleteFolderTree.
 	deleteSQL := `DELETE FROM folders WHERE id = ?`
 	result, err := d.db.Exec(deleteSQL, id)
 	if err != nil {
@@ -632,16 +1348,14 @@ func (d *Database) DeleteFolder(id string) error {
 	return nil
 }
 
-func (d *Database) GetDocumentsByFolder(folderID string) ([]models.Document, error) {
-	query := `SELECT d.id, d.title, COALESCE(d.description, '') as description, 
-              d.file_name, d.file_path, d.file_size, d.file_type, 
-              COALESCE(d.category, '') as category, 
-              COALESCE(d.folder_id, 0) as folder_id,
-              COALESCE(f.name, '') as folder_name,
-              d.created_at, d.updated_at
-              FROM documents d
+func (d *Database) GetDocumentsByFolder(folderID string, publicOnly bool) ([]models.Document, error) {
+	where := ""
+	if publicOnly {
+		where = ` AND ` + publicDocument
+	}
+	query := `SELECT ` + documentColumns + ` FROM documents d
               LEFT JOIN folders f ON d.folder_id = f.id
-              WHERE d.folder_id = ?
+              WHERE d.folder_id = ?` + where + `
               ORDER BY d.created_at DESC`
 
 	rows, err := d.db.Query(query, folderID)
@@ -652,21 +1366,8 @@ func (d *Database) GetDocumentsByFolder(folderID string) ([]models.Document, err
 
 	var documents []models.Document
 	for rows.Next() {
-		var doc models.Document
-		if err := rows.Scan(
-			&doc.ID,
-			&doc.Title,
-			&doc.Description,
-			&doc.FileName,
-			&doc.FilePath,
-			&doc.FileSize,
-			&doc.FileType,
-			&doc.Category,
-			&doc.FolderID,
-			&doc.FolderName,
-			&doc.CreatedAt,
-			&doc.UpdatedAt,
-		); err != nil {
+		doc, err := scanDocument(rows)
+		if err != nil {
 			log.Printf("Error scanning document: %v", err)
 			continue
 		}
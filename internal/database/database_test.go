@@ -0,0 +1,23 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"school-website/internal/storage"
+)
+
+// newTestDatabase creates a migrated database with local file storage in a
+// temporary directory
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	dir := t.TempDir()
+	uploadDir := filepath.Join(dir, "uploads")
+
+	db, err := New(filepath.Join(dir, "school.db"), storage.NewLocal(uploadDir), uploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
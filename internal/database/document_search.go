@@ -0,0 +1,338 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"school-website/internal/doctext"
+	"school-website/internal/models"
+	"school-website/internal/search"
+	"school-website/internal/storage"
+)
+
+// --- Document Search Operations ---
+
+// ftsColumns are the indexed columns of a document. The unicode61 tokenizer
+// folds case but not ё to е, so ё is replaced in the index; snippets of the
+// text then show е as well.
+const ftsColumns = `d.id, replace(replace(d.title, 'ё', 'е'), 'Ё', 'Е'),
+            replace(replace(COALESCE(d.description, ''), 'ё', 'е'), 'Ё', 'Е'),
+            replace(replace(COALESCE(t.content, ''), 'ё', 'е'), 'Ё', 'Е')`
+
+// searchSnippetWidth is the length of document search snippets, in characters
+const searchSnippetWidth = 160
+
+// setupDocumentSearch создает полнотекстовый индекс documents_fts. Модуль
+// FTS5 есть в go-sqlite3 только при сборке с тегом sqlite_fts5. Сервер без
+// него не запускается (см. FullTextSearch); перебор текстов документов
+// остается только для сборок без тега, например тестов.
+func (d *Database) setupDocumentSearch() error {
+	_, err := d.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
+            title, description, content,
+            tokenize = 'unicode61 remove_diacritics 2'
+        )`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return nil
+		}
+		return fmt.Errorf("error creating document search index: %v", err)
+	}
+	d.fts = true
+
+	// Индекс перестраивается, если он создан только что или разошелся с таблицей документов
+	var indexed, documents int
+	err = d.db.QueryRow(`SELECT (SELECT COUNT(*) FROM documents_fts), (SELECT COUNT(*) FROM documents)`).Scan(&indexed, &documents)
+	if err != nil {
+		return fmt.Errorf("error checking document search index: %v", err)
+	}
+	if indexed == documents {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM documents_fts`); err != nil {
+		return fmt.Errorf("error clearing document search index: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO documents_fts(rowid, title, description, content)
+            SELECT ` + ftsColumns + `
+            FROM documents d LEFT JOIN document_texts t ON t.document_id = d.id`); err != nil {
+		return fmt.Errorf("error filling document search index: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error filling document search index: %v", err)
+	}
+
+	log.Printf("Document search index rebuilt for %d documents", documents)
+	return nil
+}
+
+// FullTextSearch reports whether SQLite has FTS5 and the documents_fts
+// index is in use
+func (d *Database) FullTextSearch() bool {
+	return d.fts
+}
+
+// indexDocument updates the search index entry of a document after its
+// title, description or text changed
+func (d *Database) indexDocument(id int) error {
+	if !d.fts {
+		return nil
+	}
+	if _, err := d.db.Exec(`DELETE FROM documents_fts WHERE rowid = ?`, id); err != nil {
+		return fmt.Errorf("error updating search index of document %d: %v", id, err)
+	}
+	_, err := d.db.Exec(`INSERT INTO documents_fts(rowid, title, description, content)
+            SELECT `+ftsColumns+`
+            FROM documents d LEFT JOIN document_texts t ON t.document_id = d.id WHERE d.id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("error updating search index of document %d: %v", id, err)
+	}
+	return nil
+}
+
+// unindexDocument removes the text and the search index entry of a deleted document
+func (d *Database) unindexDocument(id string) {
+	if _, err := d.db.Exec(`DELETE FROM document_texts WHERE document_id = ?`, id); err != nil {
+		log.Printf("Warning: failed to delete text of document %s: %v", id, err)
+	}
+	if !d.fts {
+		return
+	}
+	if _, err := d.db.Exec(`DELETE FROM documents_fts WHERE rowid = ?`, id); err != nil {
+		log.Printf("Warning: failed to remove document %s from search index: %v", id, err)
+	}
+}
+
+// SaveDocumentText stores the text extracted from the current file of a
+// document and updates its search index entry
+func (d *Database) SaveDocumentText(documentID int, text string) error {
+	_, err := d.db.Exec(`INSERT INTO document_texts(document_id, content) VALUES (?, ?)
+            ON CONFLICT(document_id) DO UPDATE SET content = excluded.content`, documentID, text)
+	if err != nil {
+		return fmt.Errorf("error saving text of document %d: %v", documentID, err)
+	}
+	return d.indexDocument(documentID)
+}
+
+// SearchDocuments finds the documents whose title, description or text
+// contain all terms (see search.Terms), the most relevant first. A match in
+// the title counts more than one in the description, and that more than one
+// in the text. With publicOnly, staff-only documents are skipped. Returns
+// one page of hits and the total number of hits.
+//
+// The FTS5 index only picks the candidates (at most maxSearchCandidates,
+// best bm25 rank first); they are scored by documentHit like the documents
+// found without the index, so the score means the same in both cases.
+func (d *Database) SearchDocuments(terms []string, publicOnly bool, limit, offset int) ([]models.DocumentHit, int, error) {
+	if len(terms) == 0 {
+		return []models.DocumentHit{}, 0, nil
+	}
+	if !d.fts {
+		return d.scanDocuments(terms, publicOnly, limit, offset)
+	}
+
+	where := ""
+	if publicOnly {
+		where = ` AND ` + publicDocument
+	}
+	query := `SELECT ` + documentColumns + `, COALESCE(t.content, '')
+              FROM documents_fts
+              JOIN documents d ON d.id = documents_fts.rowid
+              LEFT JOIN folders f ON d.folder_id = f.id
+              LEFT JOIN document_texts t ON t.document_id = d.id
+              WHERE documents_fts MATCH ?` + where + `
+              ORDER BY bm25(documents_fts, 10.0, 4.0, 1.0), d.updated_at DESC
+              LIMIT ?`
+
+	rows, err := d.db.Query(query, search.FTSQuery(terms), maxSearchCandidates)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SearchDocuments query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var hits []models.DocumentHit
+	for rows.Next() {
+		var content string
+		doc, err := scanDocument(rows, &content)
+		if err != nil {
+			log.Printf("Error scanning document: %v", err)
+			continue
+		}
+		hits = append(hits, documentHit(doc, content, terms))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating search results: %v", err)
+	}
+
+	page, total := pageHits(hits, limit, offset)
+	return page, total, nil
+}
+
+// scanDocuments searches without FTS5 by reading the text of every document
+func (d *Database) scanDocuments(terms []string, publicOnly bool, limit, offset int) ([]models.DocumentHit, int, error) {
+	query := `SELECT ` + documentColumns + `, COALESCE(t.content, '')
+              FROM documents d
+              LEFT JOIN folders f ON d.folder_id = f.id
+              LEFT JOIN document_texts t ON t.document_id = d.id`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SearchDocuments query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var hits []models.DocumentHit
+	for rows.Next() {
+		var content string
+		doc, err := scanDocument(rows, &content)
+		if err != nil {
+			log.Printf("Error scanning document: %v", err)
+			continue
+		}
+		if publicOnly && !doc.Public {
+			continue
+		}
+		if !search.ContainsAll(terms, doc.Title, doc.Description, content) {
+			continue
+		}
+		hits = append(hits, documentHit(doc, content, terms))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating search results: %v", err)
+	}
+
+	page, total := pageHits(hits, limit, offset)
+	return page, total, nil
+}
+
+// documentHit scores a found document: a word in the title counts 10, in
+// the description 4 and in the text 1, with at most maxBodyMatches words of
+// the text counted. The snippet is the text around the first match, or the
+// description if only it matched.
+func documentHit(doc models.Document, content string, terms []string) models.DocumentHit {
+	contentMatches := search.Count(content, terms)
+	if contentMatches > maxBodyMatches {
+		contentMatches = maxBodyMatches
+	}
+	descriptionMatches := search.Count(doc.Description, terms)
+
+	snippetText := content
+	if contentMatches == 0 && (descriptionMatches > 0 || content == "") {
+		snippetText = doc.Description
+	}
+	return models.DocumentHit{
+		Document:  doc,
+		TitleHTML: search.Snippet(doc.Title, terms, 0),
+		Snippet:   search.Snippet(snippetText, terms, searchSnippetWidth),
+		Score:     float64(10*search.Count(doc.Title, terms) + 4*descriptionMatches + contentMatches),
+	}
+}
+
+// pageHits orders hits by score, the most recently changed first among equal
+// ones, and returns one page of them and their total number
+func pageHits(hits []models.DocumentHit, limit, offset int) ([]models.DocumentHit, int) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].UpdatedAt.After(hits[j].UpdatedAt)
+	})
+
+	total := len(hits)
+	if offset > total {
+		offset = total
+	}
+	page := hits[offset:]
+	if limit > 0 && limit < len(page) {
+		page = page[:limit]
+	}
+	if page == nil {
+		page = []models.DocumentHit{}
+	}
+	return page, total
+}
+
+// backfillDocumentTexts извлекает текст документов, загруженных до появления
+// поиска. Выполняется в фоне: чтение файлов из хранилища может быть долгим.
+// Документ, текст которого извлечь не удалось, получает пустой текст, чтобы
+// не разбирать его при каждом запуске.
+func (d *Database) backfillDocumentTexts() {
+	rows, err := d.db.Query(`SELECT id, file_path, file_type FROM documents
+            WHERE id NOT IN (SELECT document_id FROM document_texts) ORDER BY id`)
+	if err != nil {
+		log.Printf("Error finding documents without text: %v", err)
+		return
+	}
+	type pending struct {
+		id             int
+		path, fileType string
+	}
+	var documents []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.path, &p.fileType); err != nil {
+			log.Printf("Error scanning document: %v", err)
+			continue
+		}
+		documents = append(documents, p)
+	}
+	rows.Close()
+
+	indexed := 0
+	for _, p := range documents {
+		text := ""
+		if doctext.Supported(p.fileType) {
+			text, err = d.extractStoredText(p.path, p.fileType)
+			if err != nil {
+				log.Printf("Warning: failed to extract text of document %d: %v", p.id, err)
+			}
+		}
+		if err := d.SaveDocumentText(p.id, text); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		indexed++
+	}
+
+	if indexed > 0 {
+		log.Printf("Extracted text of %d documents for search", indexed)
+	}
+}
+
+// extractStoredText reads a file from the storage and extracts its text
+func (d *Database) extractStoredText(path, fileType string) (string, error) {
+	obj, err := d.files.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	file, size, err := readerAt(obj)
+	if err != nil {
+		return "", err
+	}
+	return doctext.Extract(file, size, fileType)
+}
+
+// readerAt gives random access to a stored object, which the extractors and
+// type detection need. Objects that aren't local files are read into memory.
+// The result is only valid until the object is closed.
+func readerAt(obj *storage.Object) (io.ReaderAt, int64, error) {
+	if file, ok := obj.ReadCloser.(io.ReaderAt); ok {
+		return file, obj.Size, nil
+	}
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"school-website/internal/models"
+)
+
+// --- Document Share Operations ---
+
+const shareColumns = `s.id, s.document_id, COALESCE(d.title, ''), s.note, s.expires_at, s.max_downloads, s.downloads,
+			  COALESCE(s.user_id, 0), s.username, s.revoked_at, s.created_at, s.nonce`
+
+func scanDocumentShare(scanner interface{ Scan(...interface{}) error }) (models.DocumentShare, error) {
+	var s models.DocumentShare
+	var revokedAt sql.NullTime
+	err := scanner.Scan(&s.ID, &s.DocumentID, &s.DocumentTitle, &s.Note, &s.ExpiresAt, &s.MaxDownloads, &s.Downloads,
+		&s.UserID, &s.Username, &revokedAt, &s.CreatedAt, &s.Nonce)
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return s, err
+}
+
+// CreateDocumentShare stores a new share link and returns its ID
+func (d *Database) CreateDocumentShare(s models.DocumentShare) (int64, error) {
+	result, err := d.db.Exec(`INSERT INTO document_shares(document_id, nonce, note, expires_at, max_downloads,
+                  user_id, username, created_at)
+                  VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?)`,
+		s.DocumentID, s.Nonce, s.Note, s.ExpiresAt, s.MaxDownloads, s.UserID, s.Username, s.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("error saving share link of document %d: %v", s.DocumentID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	log.Printf("Share link %d created for document %d", id, s.DocumentID)
+	return id, nil
+}
+
+func (d *Database) GetDocumentShare(id string) (models.DocumentShare, error) {
+	query := `SELECT ` + shareColumns + ` FROM document_shares s
+			  LEFT JOIN documents d ON d.id = s.document_id
+			  WHERE s.id = ?`
+
+	s, err := scanDocumentShare(d.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return s, fmt.Errorf("share link with ID %s not found", id)
+	}
+	if err != nil {
+		return s, fmt.Errorf("error getting share link with ID %s: %v", id, err)
+	}
+	return s, nil
+}
+
+// GetDocumentShares lists the share links of a document, or of all documents
+// if documentID is empty, newest first
+func (d *Database) GetDocumentShares(documentID string) ([]models.DocumentShare, error) {
+	query := `SELECT ` + shareColumns + ` FROM document_shares s
+			  LEFT JOIN documents d ON d.id = s.document_id`
+	var args []interface{}
+	if documentID != "" {
+		query += ` WHERE s.document_id = ?`
+		args = append(args, documentID)
+	}
+	query += ` ORDER BY s.created_at DESC, s.id DESC`
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetDocumentShares query failed: %v", err)
+	}
+	defer rows.Close()
+
+	shares := []models.DocumentShare{}
+	for rows.Next() {
+		s, err := scanDocumentShare(rows)
+		if err != nil {
+			log.Printf("Error scanning share link: %v", err)
+			continue
+		}
+		shares = append(shares, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating share links: %v", err)
+	}
+	return shares, nil
+}
+
+// RevokeDocumentShare disables a share link for good. Revoking a link twice
+// keeps the time of the first revocation.
+func (d *Database) RevokeDocumentShare(id string) error {
+	result, err := d.db.Exec(`UPDATE document_shares SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`,
+		time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("error revoking share link: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("share link with ID %s not found", id)
+	}
+
+	log.Printf("Share link %s revoked", id)
+	return nil
+}
+
+// CountShareDownload counts a download through a share link. It reports
+// false, counting nothing, if the link is revoked or its downloads are used
+// up, so that parallel requests can't go over the limit.
+func (d *Database) CountShareDownload(id int) (bool, error) {
+	result, err := d.db.Exec(`UPDATE document_shares SET downloads = downloads + 1
+                  WHERE id = ? AND revoked_at IS NULL AND (max_downloads = 0 OR downloads < max_downloads)`, id)
+	if err != nil {
+		return false, fmt.Errorf("error counting download of share link %d: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"school-website/internal/models"
+)
+
+// --- Document Version Operations ---
+
+const versionColumns = `id, document_id, version, file_name, original_name, file_path, file_size, file_type,
+			  note, COALESCE(user_id, 0), username, created_at`
+
+func scanDocumentVersion(scanner interface{ Scan(...interface{}) error }) (models.DocumentVersion, error) {
+	var v models.DocumentVersion
+	err := scanner.Scan(&v.ID, &v.DocumentID, &v.Version, &v.FileName, &v.OriginalName, &v.FilePath,
+		&v.FileSize, &v.FileType, &v.Note, &v.UserID, &v.Username, &v.CreatedAt)
+	return v, err
+}
+
+// SaveDocumentVersion stores a file as the next version of its document and
+// makes the document point to it, so the download URL serves the new file.
+// The version number is assigned here and returned with the saved version.
+func (d *Database) SaveDocumentVersion(v models.DocumentVersion) (models.DocumentVersion, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return v, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM document_versions WHERE document_id = ?`, v.DocumentID).Scan(&v.Version)
+	if err != nil {
+		return v, fmt.Errorf("error getting next version of document %d: %v", v.DocumentID, err)
+	}
+	v.CreatedAt = time.Now().UTC()
+
+	result, err := tx.Exec(`INSERT INTO document_versions(document_id, version, file_name, original_name, file_path,
+                  file_size, file_type, note, user_id, username, created_at)
+                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?)`,
+		v.DocumentID, v.Version, v.FileName, v.OriginalName, v.FilePath, v.FileSize, v.FileType,
+		v.Note, v.UserID, v.Username, v.CreatedAt)
+	if err != nil {
+		return v, fmt.Errorf("error saving version of document %d: %v", v.DocumentID, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return v, fmt.Errorf("error getting last insert id: %v", err)
+	}
+	v.ID = int(id)
+
+	result, err = tx.Exec(`UPDATE documents SET file_name = ?, original_name = ?, file_path = ?, file_size = ?,
+                  file_type = ?, version = ?, version_note = NULLIF(?, ''), updated_at = ? WHERE id = ?`,
+		v.FileName, v.OriginalName, v.FilePath, v.FileSize, v.FileType, v.Version, v.Note, time.Now(), v.DocumentID)
+	if err != nil {
+		return v, fmt.Errorf("error updating document %d: %v", v.DocumentID, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return v, fmt.Errorf("document with ID %d not found", v.DocumentID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return v, fmt.Errorf("error committing version of document %d: %v", v.DocumentID, err)
+	}
+
+	log.Printf("Document %d: version %d saved (%s)", v.DocumentID, v.Version, v.OriginalName)
+	return v, nil
+}
+
+// GetDocumentVersions lists the versions of a document, newest first
+func (d *Database) GetDocumentVersions(documentID string) ([]models.DocumentVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM document_versions WHERE document_id = ? ORDER BY version DESC`
+
+	rows, err := d.db.Query(query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("GetDocumentVersions query failed: %v", err)
+	}
+	defer rows.Close()
+
+	versions := []models.DocumentVersion{}
+	for rows.Next() {
+		v, err := scanDocumentVersion(rows)
+		if err != nil {
+			log.Printf("Error scanning document version: %v", err)
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document versions: %v", err)
+	}
+
+	return versions, nil
+}
+
+// GetDocumentVersion returns a single version of a document by its number
+func (d *Database) GetDocumentVersion(documentID string, version int) (models.DocumentVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM document_versions WHERE document_id = ? AND version = ?`
+
+	v, err := scanDocumentVersion(d.db.QueryRow(query, documentID, version))
+	if err == sql.ErrNoRows {
+		return v, fmt.Errorf("version %d of document %s not found", version, documentID)
+	}
+	if err != nil {
+		return v, fmt.Errorf("error getting version %d of document %s: %v", version, documentID, err)
+	}
+
+	return v, nil
+}
+
+// documentFiles returns the storage keys of the current file and all earlier
+// versions of a document
+func (d *Database) documentFiles(documentID string) ([]string, error) {
+	rows, err := d.db.Query(`SELECT file_path FROM documents WHERE id = ?1
+		UNION SELECT file_path FROM document_versions WHERE document_id = ?1`, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// backfillDocumentVersions сохраняет текущий файл документов без истории как первую версию
+func (d *Database) backfillDocumentVersions() error {
+	result, err := d.db.Exec(`INSERT INTO document_versions(document_id, version, file_name, original_name, file_path,
+                  file_size, file_type, note, created_at)
+                  SELECT id, version, file_name, COALESCE(original_name, file_name), file_path, file_size, file_type,
+                  COALESCE(version_note, ''), COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+                  FROM documents WHERE id NOT IN (SELECT document_id FROM document_versions)`)
+	if err != nil {
+		return fmt.Errorf("error backfilling document versions: %v", err)
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Created initial versions for %d documents", n)
+	}
+	return nil
+}
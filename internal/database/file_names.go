@@ -0,0 +1,275 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"school-website/internal/filetype"
+	"school-website/internal/storage"
+)
+
+// --- Stored File Names ---
+
+// legacyPrefix is the timestamp older uploads were prefixed with ("1700000000_")
+var legacyPrefix = regexp.MustCompile(`^\d+_`)
+
+// variantSuffix splits the size suffix off the name of a resized image
+var variantSuffix = regexp.MustCompile(`^(.*)(_(?:thumb|card|full))(\.[A-Za-z0-9]+)$`)
+
+// renameStoredFiles переименовывает файлы, загруженные до появления случайных
+// имен: документы и изображения новостей. Уже переименованные файлы пропускаются,
+// поэтому повторный запуск ничего не меняет.
+func (d *Database) renameStoredFiles() error {
+	if err := d.renameDocumentFiles(); err != nil {
+		return fmt.Errorf("error renaming document files: %v", err)
+	}
+	if err := d.renameNewsImageFiles(); err != nil {
+		return fmt.Errorf("error renaming news image files: %v", err)
+	}
+	return nil
+}
+
+// convertDocumentPaths превращает пути к файлам документов на диске
+// ("public/uploads/documents/x.pdf") в ключи хранилища ("documents/x.pdf")
+func (d *Database) convertDocumentPaths() error {
+	prefix := filepath.ToSlash(filepath.Clean(d.uploadDir)) + "/"
+	_, err := d.db.Exec(`UPDATE documents SET file_path = substr(file_path, length(?1) + 1)
+		WHERE substr(file_path, 1, length(?1)) = ?1`, prefix)
+	return err
+}
+
+// renameDocumentFiles дает документам без original_name случайные имена файлов,
+// сохраняя исходное имя. Тип файла заново определяется по содержимому.
+func (d *Database) renameDocumentFiles() error {
+	type legacyDocument struct {
+		id                 int
+		fileName, filePath string
+		fileType           string
+	}
+
+	rows, err := d.db.Query(`SELECT id, file_name, file_path, file_type FROM documents WHERE original_name IS NULL`)
+	if err != nil {
+		return err
+	}
+	var docs []legacyDocument
+	for rows.Next() {
+		var doc legacyDocument
+		if err := rows.Scan(&doc.id, &doc.fileName, &doc.filePath, &doc.fileType); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	rows.Close()
+
+	for _, doc := range docs {
+		originalName := storage.CleanName(legacyPrefix.ReplaceAllString(doc.fileName, ""))
+		fileName, filePath, fileType := doc.fileName, doc.filePath, doc.fileType
+
+		if !storage.IsGenerated(doc.fileName) && storage.ValidKey(doc.filePath) {
+			renamed, detected, err := d.renameLegacyDocument(doc.filePath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Warning: failed to rename file of document %d: %v", doc.id, err)
+				continue
+			}
+			if err == nil {
+				fileName, filePath, fileType = path.Base(renamed), renamed, detected
+			}
+		}
+
+		_, err := d.db.Exec(`UPDATE documents SET file_name = ?, file_path = ?, file_type = ?, original_name = ? WHERE id = ?`,
+			fileName, filePath, fileType, originalName, doc.id)
+		if err != nil {
+			if filePath != doc.filePath {
+				d.moveStoredFile(filePath, doc.filePath)
+			}
+			return err
+		}
+		if filePath != doc.filePath {
+			log.Printf("Document %d: file %s renamed to %s", doc.id, doc.filePath, filePath)
+		}
+	}
+	return nil
+}
+
+// renameLegacyDocument moves a stored document to a random key with the
+// extension of its detected type and returns the new key and the type
+func (d *Database) renameLegacyDocument(key string) (string, string, error) {
+	obj, err := d.files.Open(key)
+	if err != nil {
+		return "", "", err
+	}
+	detected, err := detectStoredType(obj, key)
+	obj.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	baseName, err := storage.NewName()
+	if err != nil {
+		return "", "", err
+	}
+	ext := filetype.Extension(detected)
+	if ext == "" {
+		ext = ".bin"
+	}
+
+	newKey := path.Join(path.Dir(key), baseName+ext)
+	if err := d.moveStoredFile(key, newKey); err != nil {
+		return "", "", err
+	}
+	return newKey, detected, nil
+}
+
+// detectStoredType detects the type of a stored object by its content
+func detectStoredType(obj *storage.Object, key string) (string, error) {
+	file, size, err := readerAt(obj)
+	if err != nil {
+		return "", err
+	}
+	return filetype.Detect(file, size, key)
+}
+
+// renameNewsImageFiles дает изображениям новостей (обложки, варианты размеров,
+// галереи) случайные имена и заменяет старые адреса в новостях, галереях и ревизиях
+func (d *Database) renameNewsImageFiles() error {
+	urls, err := d.legacyImageURLs()
+	if err != nil || len(urls) == 0 {
+		return err
+	}
+
+	// Variants of one upload share a prefix and get the same new one
+	prefixes := map[string]string{}
+	renamed := map[string]string{}
+	for _, url := range urls {
+		name, _ := storage.KeyFromURL(url)
+		prefix, suffix, ext := name, "", filepath.Ext(name)
+		if m := variantSuffix.FindStringSubmatch(name); m != nil {
+			prefix, suffix, ext = m[1], m[2], m[3]
+		} else {
+			prefix = strings.TrimSuffix(name, ext)
+		}
+
+		if _, ok := prefixes[prefix]; !ok {
+			baseName, err := storage.NewName()
+			if err != nil {
+				return err
+			}
+			prefixes[prefix] = baseName
+		}
+		newName := prefixes[prefix] + suffix + strings.ToLower(ext)
+
+		if err := d.moveStoredFile(name, newName); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Warning: failed to rename %s: %v", url, err)
+			}
+			continue
+		}
+		renamed[url] = storage.URL(newName)
+	}
+	if len(renamed) == 0 {
+		return nil
+	}
+
+	if err := d.replaceImageURLs(renamed); err != nil {
+		for oldURL, newURL := range renamed {
+			oldKey, _ := storage.KeyFromURL(oldURL)
+			newKey, _ := storage.KeyFromURL(newURL)
+			d.moveStoredFile(newKey, oldKey)
+		}
+		return err
+	}
+
+	log.Printf("Renamed %d news image files", len(renamed))
+	return nil
+}
+
+// moveStoredFile renames a file in the file storage. Storage has no rename,
+// so the file is copied to the new key and the old one is deleted.
+func (d *Database) moveStoredFile(oldKey, newKey string) error {
+	file, err := d.files.Open(oldKey)
+	if err != nil {
+		return err
+	}
+	err = d.files.Put(newKey, file, file.Size, file.ContentType)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	return d.files.Delete(oldKey)
+}
+
+// legacyImageURLs returns the uploaded images referenced by news, galleries and
+// revisions whose file names were not generated by the server
+func (d *Database) legacyImageURLs() ([]string, error) {
+	rows, err := d.db.Query(`SELECT COALESCE(image_url, ''), COALESCE(image_variants, '') FROM news
+		UNION ALL SELECT url, COALESCE(variants, '') FROM news_images
+		UNION ALL SELECT COALESCE(image_url, ''), COALESCE(image_variants, '') FROM news_revisions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := map[string]bool{}
+	var urls []string
+	for rows.Next() {
+		var url, variants string
+		if err := rows.Scan(&url, &variants); err != nil {
+			return nil, err
+		}
+		candidates := []string{url}
+		for _, v := range decodeVariants(variants) {
+			candidates = append(candidates, v.URL)
+		}
+		for _, u := range candidates {
+			name := strings.TrimPrefix(u, storage.URLPrefix)
+			if name == u || name == "" || strings.ContainsAny(name, `/\`) || storage.IsGenerated(name) || seen[u] {
+				continue
+			}
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls, rows.Err()
+}
+
+// replaceImageURLs заменяет адреса переименованных файлов во всех местах,
+// где они могут встречаться, включая текст новостей
+func (d *Database) replaceImageURLs(renamed map[string]string) error {
+	// Longer addresses first, so that no address is replaced inside another one
+	oldURLs := make([]string, 0, len(renamed))
+	for url := range renamed {
+		oldURLs = append(oldURLs, url)
+	}
+	sort.Slice(oldURLs, func(i, j int) bool { return len(oldURLs[i]) > len(oldURLs[j]) })
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updates := []string{
+		`UPDATE news SET image_url = REPLACE(image_url, ?1, ?2), image_variants = REPLACE(image_variants, ?1, ?2),
+			content = REPLACE(content, ?1, ?2), content_html = REPLACE(content_html, ?1, ?2)`,
+		`UPDATE news_images SET url = REPLACE(url, ?1, ?2), variants = REPLACE(variants, ?1, ?2)`,
+		`UPDATE news_revisions SET image_url = REPLACE(image_url, ?1, ?2), image_variants = REPLACE(image_variants, ?1, ?2),
+			content = REPLACE(content, ?1, ?2)`,
+	}
+	for _, oldURL := range oldURLs {
+		for _, update := range updates {
+			if _, err := tx.Exec(update, oldURL, renamed[oldURL]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"school-website/internal/models"
+)
+
+// --- Folder Tree Operations ---
+
+// migrateFolderTree пересоздает таблицу folders с колонкой parent_id: в старой
+// схеме имя папки уникально во всей библиотеке, а SQLite не умеет снимать
+// ограничение UNIQUE. Теперь имя уникально только среди папок одного родителя.
+func (d *Database) migrateFolderTree() error {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('folders') WHERE name = 'parent_id'`).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("error checking column parent_id in table folders: %v", err)
+	}
+
+	if count == 0 {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		statements := []string{
+			`CREATE TABLE folders_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                parent_id INTEGER REFERENCES folders(id),
+                name TEXT NOT NULL,
+                description TEXT,
+                icon TEXT DEFAULT 'folder',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+            )`,
+			`INSERT INTO folders_new (id, name, description, icon, created_at)
+                SELECT id, name, description, icon, created_at FROM folders`,
+			`DROP TABLE folders`,
+			`ALTER TABLE folders_new RENAME TO folders`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("error rebuilding folders table: %v", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error rebuilding folders table: %v", err)
+		}
+		log.Printf("Added column parent_id to table folders")
+	}
+
+	if _, err := d.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_parent_name ON folders(COALESCE(parent_id, 0), name)`); err != nil {
+		return fmt.Errorf("error creating folder name index: %v", err)
+	}
+	return nil
+}
+
+// FolderNameTaken reports whether another folder (not excludeID) with the same
+// parent already has the name
+func (d *Database) FolderNameTaken(parentID int, name string, excludeID int) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM folders WHERE COALESCE(parent_id, 0) = ? AND name = ? AND id != ?`,
+		parentID, name, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+// GetFolderPath returns the breadcrumbs of a folder: its ancestors from the
+// top-level folder down to the folder itself
+func (d *Database) GetFolderPath(id string) ([]models.Breadcrumb, error) {
+	query := `WITH RECURSIVE path(id, name, parent_id, depth) AS (
+                SELECT id, name, parent_id, 0 FROM folders WHERE id = ?
+                UNION
+                SELECT f.id, f.name, f.parent_id, p.depth + 1 FROM folders f JOIN path p ON f.id = p.parent_id
+              )
+              SELECT id, name FROM path ORDER BY depth DESC`
+
+	rows, err := d.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("GetFolderPath query failed: %v", err)
+	}
+	defer rows.Close()
+
+	path := []models.Breadcrumb{}
+	for rows.Next() {
+		var crumb models.Breadcrumb
+		if err := rows.Scan(&crumb.ID, &crumb.Name); err != nil {
+			return nil, fmt.Errorf("error scanning folder path: %v", err)
+		}
+		path = append(path, crumb)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating folder path: %v", err)
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("folder with ID %s not found", id)
+	}
+	return path, nil
+}
+
+// GetFolderSubtree returns the IDs of a folder and all folders below it
+func (d *Database) GetFolderSubtree(id int) ([]int, error) {
+	query := `WITH RECURSIVE subtree(id) AS (
+                SELECT id FROM folders WHERE id = ?
+                UNION
+                SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id
+              )
+              SELECT id FROM subtree`
+
+	rows, err := d.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("GetFolderSubtree query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var folderID int
+		if err := rows.Scan(&folderID); err != nil {
+			return nil, fmt.Errorf("error scanning folder subtree: %v", err)
+		}
+		ids = append(ids, folderID)
+	}
+	return ids, rows.Err()
+}
+
+// GetSubfolders lists the folders directly inside a folder; with publicOnly
+// only the ones visitors may see
+func (d *Database) GetSubfolders(parentID string, publicOnly bool) ([]models.Folder, error) {
+	where := ""
+	if publicOnly {
+		where = `AND ` + publicFolder + ` `
+	}
+	query := `SELECT ` + folderColumns(publicOnly) + ` FROM folders WHERE parent_id = ? ` + where + folderOrder
+
+	rows, err := d.db.Query(query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("GetSubfolders query failed: %v", err)
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			log.Printf("Error scanning folder: %v", err)
+			continue
+		}
+		folders = append(folders, folder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subfolders: %v", err)
+	}
+	return folders, nil
+}
+
+// CountFolderContents returns the number of sub-folders and documents
+// directly inside a folder
+func (d *Database) CountFolderContents(id string) (folders, documents int, err error) {
+	err = d.db.QueryRow(`SELECT (SELECT COUNT(*) FROM folders WHERE parent_id = ?1),
+                (SELECT COUNT(*) FROM documents WHERE folder_id = ?1)`, id).Scan(&folders, &documents)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting contents of folder %s: %v", id, err)
+	}
+	return folders, documents, nil
+}
+
+// MoveFolder puts a folder inside another one, after its new siblings, or at
+// the top level if parentID is zero. Callers check for cycles with
+// GetFolderSubtree.
+func (d *Database) MoveFolder(id string, parentID int) error {
+	result, err := d.db.Exec(`UPDATE folders SET parent_id = NULLIF(?1, 0),
+                sort_order = (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM folders WHERE COALESCE(parent_id, 0) = ?1)
+                WHERE id = ?2`, parentID, id)
+	if err != nil {
+		return fmt.Errorf("error moving folder: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("folder with ID %s not found", id)
+	}
+
+	log.Printf("Folder with ID %s moved to folder %d", id, parentID)
+	return nil
+}
+
+// UpdateFolder saves the name, description, icon and visibility of a folder
+func (d *Database) UpdateFolder(folder models.Folder) error {
+	result, err := d.db.Exec(`UPDATE folders SET name = ?, description = ?, icon = ?, visibility = ?, search_text = ? WHERE id = ?`,
+		folder.Name, folder.Description, folder.Icon, folder.Visibility, searchText(folder.Name, folder.Description), folder.ID)
+	if err != nil {
+		return fmt.Errorf("error updating folder: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("folder with ID %d not found", folder.ID)
+	}
+
+	log.Printf("Folder with ID %d successfully updated", folder.ID)
+	return nil
+}
+
+// ReorderFolders sets the order of the folders directly inside parentID (0:
+// the top-level folders). folderIDs must list each of them exactly once.
+func (d *Database) ReorderFolders(parentID int, folderIDs []int) error {
+	rows, err := d.db.Query(`SELECT id FROM folders WHERE COALESCE(parent_id, 0) = ?`, parentID)
+	if err != nil {
+		return fmt.Errorf("ReorderFolders query failed: %v", err)
+	}
+	known := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning folder: %v", err)
+		}
+		known[id] = true
+	}
+	rows.Close()
+
+	if len(folderIDs) != len(known) {
+		return fmt.Errorf("invalid order: expected %d folders, got %d", len(known), len(folderIDs))
+	}
+	for _, id := range folderIDs {
+		if !known[id] {
+			return fmt.Errorf("invalid order: folder %d is not in this folder or listed twice", id)
+		}
+		delete(known, id)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for i, id := range folderIDs {
+		if _, err := tx.Exec(`UPDATE folders SET sort_order = ? WHERE id = ?`, i+1, id); err != nil {
+			return fmt.Errorf("error reordering folders: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing folder order: %v", err)
+	}
+	return nil
+}
+
+// DeleteFolderTree deletes a folder with all its sub-folders and the
+// documents in them, files included, and returns the deleted documents
+func (d *Database) DeleteFolderTree(id string) ([]models.Document, error) {
+	folderID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("folder with ID %s not found", id)
+	}
+	subtree, err := d.GetFolderSubtree(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subtree) == 0 {
+		return nil, fmt.Errorf("folder with ID %s not found", id)
+	}
+
+	var deleted []models.Document
+	for _, subfolderID := range subtree {
+		documents, err := d.GetDocumentsByFolder(strconv.Itoa(subfolderID), false)
+		if err != nil {
+			return deleted, err
+		}
+		for _, doc := range documents {
+			if err := d.DeleteDocument(strconv.Itoa(doc.ID)); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, doc)
+		}
+	}
+
+	// Deepest folders first, so that no folder is left pointing at a deleted parent
+	for i := len(subtree) - 1; i >= 0; i-- {
+		if _, err := d.db.Exec(`DELETE FROM folders WHERE id = ?`, subtree[i]); err != nil {
+			return deleted, fmt.Errorf("error deleting folder %d: %v", subtree[i], err)
+		}
+	}
+
+	log.Printf("Folder with ID %s deleted with %d sub-folders and %d documents", id, len(subtree)-1, len(deleted))
+	return deleted, nil
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"school-website/internal/models"
+	"school-website/internal/storage"
+)
+
+// --- News Gallery Operations ---
+
+const newsImageColumns = `id, news_id, url, COALESCE(variants, ''), caption, position, created_at`
+
+func scanNewsImage(scanner interface{ Scan(...interface{}) error }) (models.NewsImage, error) {
+	var img models.NewsImage
+	var variants string
+	err := scanner.Scan(&img.ID, &img.NewsID, &img.URL, &variants, &img.Caption, &img.Position, &img.CreatedAt)
+	img.Variants = decodeVariants(variants)
+	return img, err
+}
+
+// AddNewsImages appends images (URL and resized variants) to the end of the
+// article's gallery
+func (d *Database) AddNewsImages(newsID int, uploaded []models.NewsImage) ([]models.NewsImage, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var position int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(position), 0) FROM news_images WHERE news_id = ?`, newsID).Scan(&position); err != nil {
+		return nil, fmt.Errorf("error reading gallery of news %d: %v", newsID, err)
+	}
+
+	images := make([]models.NewsImage, 0, len(uploaded))
+	for _, img := range uploaded {
+		position++
+		now := time.Now()
+		result, err := tx.Exec(`INSERT INTO news_images(news_id, url, variants, position, created_at) VALUES (?, ?, ?, ?, ?)`,
+			newsID, img.URL, encodeVariants(img.Variants), position, now)
+		if err != nil {
+			return nil, fmt.Errorf("error adding image to news %d: %v", newsID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("error getting last insert id: %v", err)
+		}
+		images = append(images, models.NewsImage{ID: int(id), NewsID: newsID, URL: img.URL, Variants: img.Variants,
+			Position: position, CreatedAt: now})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing gallery images: %v", err)
+	}
+
+	log.Printf("Added %d images to gallery of news %d", len(images), newsID)
+	return images, nil
+}
+
+// GetNewsImages returns the gallery of an article in display order
+func (d *Database) GetNewsImages(newsID int) ([]models.NewsImage, error) {
+	rows, err := d.db.Query(`SELECT `+newsImageColumns+` FROM news_images WHERE news_id = ? ORDER BY position, id`, newsID)
+	if err != nil {
+		return nil, fmt.Errorf("GetNewsImages query failed: %v", err)
+	}
+	defer rows.Close()
+
+	images := []models.NewsImage{}
+	for rows.Next() {
+		img, err := scanNewsImage(rows)
+		if err != nil {
+			log.Printf("Error scanning news image: %v", err)
+			continue
+		}
+		images = append(images, img)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating news images: %v", err)
+	}
+
+	return images, nil
+}
+
+func (d *Database) GetNewsImage(newsID int, imageID string) (models.NewsImage, error) {
+	img, err := scanNewsImage(d.db.QueryRow(`SELECT `+newsImageColumns+` FROM news_images WHERE news_id = ? AND id = ?`,
+		newsID, imageID))
+	if err == sql.ErrNoRows {
+		return img, fmt.Errorf("image %s of news %d not found", imageID, newsID)
+	}
+	if err != nil {
+		return img, fmt.Errorf("error getting image %s of news %d: %v", imageID, newsID, err)
+	}
+	return img, nil
+}
+
+func (d *Database) UpdateNewsImageCaption(newsID int, imageID, caption string) error {
+	result, err := d.db.Exec(`UPDATE news_images SET caption = ? WHERE news_id = ? AND id = ?`, caption, newsID, imageID)
+	if err != nil {
+		return fmt.Errorf("error updating image %s of news %d: %v", imageID, newsID, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("image %s of news %d not found", imageID, newsID)
+	}
+	return nil
+}
+
+// ReorderNewsImages sets the gallery order. imageIDs must list every image
+// of the article exactly once.
+func (d *Database) ReorderNewsImages(newsID int, imageIDs []int) error {
+	current, err := d.GetNewsImages(newsID)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(current))
+	for _, img := range current {
+		known[img.ID] = true
+	}
+	if len(imageIDs) != len(current) {
+		return fmt.Errorf("invalid order: expected %d images, got %d", len(current), len(imageIDs))
+	}
+	for _, id := range imageIDs {
+		if !known[id] {
+			return fmt.Errorf("invalid order: image %d is not in the gallery or listed twice", id)
+		}
+		delete(known, id)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for i, id := range imageIDs {
+		if _, err := tx.Exec(`UPDATE news_images SET position = ? WHERE news_id = ? AND id = ?`, i+1, newsID, id); err != nil {
+			return fmt.Errorf("error reordering gallery of news %d: %v", newsID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing gallery order: %v", err)
+	}
+	return nil
+}
+
+// DeleteNewsImage removes an image from the gallery together with its file
+func (d *Database) DeleteNewsImage(newsID int, imageID string) error {
+	img, err := d.GetNewsImage(newsID, imageID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := d.db.Exec(`DELETE FROM news_images WHERE id = ?`, img.ID); err != nil {
+		return fmt.Errorf("error deleting image %s of news %d: %v", imageID, newsID, err)
+	}
+
+	for _, url := range models.ImageFiles(img.URL, img.Variants) {
+		d.removeUpload(url)
+	}
+	return nil
+}
+
+// deleteNewsImages удаляет галерею новости и возвращает адреса удаленных файлов (со всеми размерами)
+func (d *Database) deleteNewsImages(newsID string) ([]string, error) {
+	rows, err := d.db.Query(`SELECT url, COALESCE(variants, '') FROM news_images WHERE news_id = ?`, newsID)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for rows.Next() {
+		var url, variants string
+		if err := rows.Scan(&url, &variants); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		urls = append(urls, models.ImageFiles(url, decodeVariants(variants))...)
+	}
+	rows.Close()
+
+	if _, err := d.db.Exec(`DELETE FROM news_images WHERE news_id = ?`, newsID); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// removeUpload удаляет загруженный файл по его адресу (/uploads/...)
+func (d *Database) removeUpload(url string) {
+	// image_url новости вводится вручную, поэтому внешние адреса пропускаем
+	key, ok := storage.KeyFromURL(url)
+	if !ok {
+		return
+	}
+
+	if err := d.files.Delete(key); err != nil {
+		log.Printf("Warning: failed to delete file %s: %v", key, err)
+	} else {
+		log.Printf("File %s successfully deleted", key)
+	}
+}
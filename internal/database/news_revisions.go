@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"school-website/internal/models"
+)
+
+// --- News Revision Operations ---
+
+const revisionColumns = `id, news_id, number, title, COALESCE(slug, ''), content, COALESCE(image_url, ''),
+			  COALESCE(image_variants, ''), status, publish_at, action, COALESCE(restored_from, 0), user_id, username, created_at`
+
+func scanRevision(scanner interface{ Scan(...interface{}) error }) (models.NewsRevision, error) {
+	var rev models.NewsRevision
+	var publishAt sql.NullTime
+	var variants string
+	err := scanner.Scan(&rev.ID, &rev.NewsID, &rev.Number, &rev.Title, &rev.Slug, &rev.Content, &rev.ImageURL,
+		&variants, &rev.Status, &publishAt, &rev.Action, &rev.RestoredFrom, &rev.UserID, &rev.Username, &rev.CreatedAt)
+	if publishAt.Valid {
+		rev.PublishAt = &publishAt.Time
+	}
+	rev.ImageVariants = decodeVariants(variants)
+	return rev, err
+}
+
+// SaveNewsRevision stores a snapshot of the article with the next revision
+// number of that article and returns the number.
+func (d *Database) SaveNewsRevision(article models.NewsArticle, action string, restoredFrom, userID int, username string) (int, error) {
+	insertSQL := `INSERT INTO news_revisions(news_id, number, title, slug, content, image_url, image_variants, status,
+                  publish_at, action, restored_from, user_id, username, created_at)
+                  SELECT ?, COALESCE(MAX(number), 0) + 1, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?
+                  FROM news_revisions WHERE news_id = ?`
+
+	result, err := d.db.Exec(insertSQL, article.ID, article.Title, article.Slug, article.Content, article.ImageURL,
+		encodeVariants(article.ImageVariants), article.Status, nullableTime(article.PublishAt), action, restoredFrom, userID, username, time.Now().UTC(), article.ID)
+	if err != nil {
+		return 0, fmt.Errorf("error saving revision of news %d: %v", article.ID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	var number int
+	if err := d.db.QueryRow(`SELECT number FROM news_revisions WHERE id = ?`, id).Scan(&number); err != nil {
+		return 0, fmt.Errorf("error reading revision number: %v", err)
+	}
+
+	return number, nil
+}
+
+// revisionImageFiles returns the uploaded cover images (with their resized
+// variants) that the revisions of an article refer to. Covers replaced by
+// an edit are kept for these revisions, so that restoring one brings its
+// image back.
+func (d *Database) revisionImageFiles(newsID string) ([]string, error) {
+	rows, err := d.db.Query(`SELECT COALESCE(image_url, ''), COALESCE(image_variants, '') FROM news_revisions WHERE news_id = ?`, newsID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading revision images of news %s: %v", newsID, err)
+	}
+	defer rows.Close()
+
+	var files []string
+	seen := map[string]bool{}
+	for rows.Next() {
+		var imageURL, variants string
+		if err := rows.Scan(&imageURL, &variants); err != nil {
+			return nil, fmt.Errorf("error scanning revision images of news %s: %v", newsID, err)
+		}
+		for _, url := range models.ImageFiles(imageURL, decodeVariants(variants)) {
+			if !seen[url] {
+				seen[url] = true
+				files = append(files, url)
+			}
+		}
+	}
+	return files, rows.Err()
+}
+
+// uploadInUse reports whether a news article, gallery photo or revision
+// still refers to the file
+func (d *Database) uploadInUse(url string) bool {
+	var count int
+	err := d.db.QueryRow(`SELECT
+            (SELECT COUNT(*) FROM news WHERE image_url = ?1 OR instr(image_variants, ?2) > 0) +
+            (SELECT COUNT(*) FROM news_images WHERE url = ?1 OR instr(variants, ?2) > 0) +
+            (SELECT COUNT(*) FROM news_revisions WHERE image_url = ?1 OR instr(image_variants, ?2) > 0)`,
+		url, `"`+url+`"`).Scan(&count)
+	if err != nil {
+		// Keeping a file is safer than deleting one that is still shown
+		log.Printf("Warning: failed to check references to %s: %v", url, err)
+		return true
+	}
+	return count > 0
+}
+
+// GetNewsRevisions lists the revisions of an article, newest first, without their content
+func (d *Database) GetNewsRevisions(newsID string) ([]models.NewsRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM news_revisions WHERE news_id = ? ORDER BY number DESC`
+
+	rows, err := d.db.Query(query, newsID)
+	if err != nil {
+		return nil, fmt.Errorf("GetNewsRevisions query failed: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.NewsRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			log.Printf("Error scanning news revision: %v", err)
+			continue
+		}
+		rev.Content = ""
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating news revisions: %v", err)
+	}
+
+	return revisions, nil
+}
+
+// GetNewsRevision returns a single revision of an article by its number
+func (d *Database) GetNewsRevision(newsID string, number int) (models.NewsRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM news_revisions WHERE news_id = ? AND number = ?`
+
+	rev, err := scanRevision(d.db.QueryRow(query, newsID, number))
+	if err == sql.ErrNoRows {
+		return rev, fmt.Errorf("revision %d of news %s not found", number, newsID)
+	}
+	if err != nil {
+		return rev, fmt.Errorf("error getting revision %d of news %s: %v", number, newsID, err)
+	}
+
+	return rev, nil
+}
+
+// backfillNewsRevisions сохраняет текущее состояние новостей без истории как первую ревизию
+func (d *Database) backfillNewsRevisions() error {
+	result, err := d.db.Exec(`INSERT INTO news_revisions(news_id, number, title, slug, content, image_url, image_variants,
+                  status, publish_at, action, created_at)
+                  SELECT id, 1, title, slug, content, image_url, image_variants, status, publish_at, ?, created_at
+                  FROM news WHERE id NOT IN (SELECT news_id FROM news_revisions)`, models.AuditCreate)
+	if err != nil {
+		return fmt.Errorf("error backfilling news revisions: %v", err)
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Created initial revisions for %d news articles", n)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"school-website/internal/models"
+	"school-website/internal/storage"
+)
+
+func TestDeleteNewsArticleRemovesRevisionImages(t *testing.T) {
+	db := newTestDatabase(t)
+
+	for _, key := range []string{"old.jpg", "old_thumb.webp", "shared.jpg", "new.jpg"} {
+		if err := db.files.Put(key, strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(key string) bool {
+		obj, err := db.files.Open(key)
+		if errors.Is(err, os.ErrNotExist) {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj.Close()
+		return true
+	}
+
+	// The article's cover was replaced twice; each revision remembers its own
+	article := models.NewsArticle{Title: "Спартакиада", ImageURL: storage.URL("new.jpg"), Status: models.NewsPublished}
+	id, err := db.SaveNews(article)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article.ID = int(id)
+	covers := []models.NewsArticle{article, article, article}
+	covers[0].ImageURL = storage.URL("old.jpg")
+	covers[0].ImageVariants = []models.ImageVariant{{Size: "thumb", Type: "image/webp", URL: storage.URL("old_thumb.webp")}}
+	covers[1].ImageURL = storage.URL("shared.jpg")
+	for _, rev := range covers {
+		if _, err := db.SaveNewsRevision(rev, "update", 0, 1, "admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Another article still shows one of the old covers
+	if _, err := db.SaveNews(models.NewsArticle{Title: "Итоги", ImageURL: storage.URL("shared.jpg"), Status: models.NewsPublished}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteNewsArticle(strconv.Itoa(article.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"new.jpg": false, "old.jpg": false, "old_thumb.webp": false, "shared.jpg": true} {
+		if got := exists(key); got != want {
+			t.Errorf("after delete, %s exists = %v, want %v", key, got, want)
+		}
+	}
+	revisions, err := db.GetNewsRevisions(strconv.Itoa(article.ID))
+	if err != nil || len(revisions) != 0 {
+		t.Errorf("revisions left: %d, %v", len(revisions), err)
+	}
+}
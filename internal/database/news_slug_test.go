@@ -0,0 +1,70 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+
+	"school-website/internal/models"
+)
+
+func TestSaveNewsSlugCollisions(t *testing.T) {
+	db := newTestDatabase(t)
+
+	save := func(title string) models.NewsArticle {
+		t.Helper()
+		id, err := db.SaveNews(models.NewsArticle{Title: title, Status: models.NewsPublished})
+		if err != nil {
+			t.Fatal(err)
+		}
+		article, err := db.GetNewsArticle(strconv.FormatInt(id, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return article
+	}
+
+	tests := []struct {
+		title    string
+		wantSlug string
+	}{
+		{"День знаний", "den-znaniy"},
+		{"День знаний", "den-znaniy-2"},
+		{"ДЕНЬ ЗНАНИЙ!", "den-znaniy-3"},
+		{"2024", "news-2024"},
+		{"!!!", "news"},
+		{"***", "news-2"},
+	}
+	for _, tt := range tests {
+		article := save(tt.title)
+		if article.Slug != tt.wantSlug {
+			t.Errorf("slug of %q = %q, want %q", tt.title, article.Slug, tt.wantSlug)
+		}
+		if article.URL != "/news/"+tt.wantSlug {
+			t.Errorf("URL of %q = %q", tt.title, article.URL)
+		}
+
+		found, err := db.GetNewsArticleBySlug(tt.wantSlug)
+		if err != nil || found.ID != article.ID {
+			t.Errorf("GetNewsArticleBySlug(%q) = article %d, %v; want %d", tt.wantSlug, found.ID, err, article.ID)
+		}
+	}
+}
+
+func TestUniqueNewsSlugKeepsOwnSlug(t *testing.T) {
+	db := newTestDatabase(t)
+
+	id, err := db.SaveNews(models.NewsArticle{Title: "Выпускной", Status: models.NewsDraft})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Editing the article doesn't make its slug collide with itself
+	got, err := db.UniqueNewsSlug("Выпускной", int(id))
+	if err != nil || got != "vypusknoy" {
+		t.Errorf("UniqueNewsSlug for the same article = %q, %v; want vypusknoy", got, err)
+	}
+	got, err = db.UniqueNewsSlug("Выпускной", 0)
+	if err != nil || got != "vypusknoy-2" {
+		t.Errorf("UniqueNewsSlug for a new article = %q, %v; want vypusknoy-2", got, err)
+	}
+}
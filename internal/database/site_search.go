@@ -0,0 +1,300 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"school-website/internal/markup"
+	"school-website/internal/models"
+	"school-website/internal/search"
+)
+
+// --- Site Search Operations ---
+
+// siteSearchSnippetWidth is the length of result snippets, in characters
+const siteSearchSnippetWidth = 200
+
+// maxBodyMatches caps how much repeated words in a long text add to the
+// relevance, so that a long article doesn't outrank a matching title
+const maxBodyMatches = 20
+
+// maxSearchCandidates caps the rows of each type read for one search. The
+// newest candidates are kept, so a very common word still answers quickly.
+const maxSearchCandidates = 500
+
+// SearchSite finds published news and public documents and folders containing all
+// terms, the most relevant first. SQLite only folds the case of Latin
+// letters, so every row keeps a folded copy of its text in search_text:
+// SQL picks the rows containing all terms there (see maxSearchCandidates)
+// and only those are matched and ranked here, which handles Russian and
+// Kazakh text as well. Returns a page of results, the total number of results of
+// the selected types and the number of results of each type, types not in
+// the filter included.
+func (d *Database) SearchSite(filter models.SearchFilter) ([]models.SearchResult, int, map[string]int, error) {
+	counts := map[string]int{}
+	for _, t := range models.SearchTypes {
+		counts[t] = 0
+	}
+	if len(filter.Terms) == 0 {
+		return []models.SearchResult{}, 0, counts, nil
+	}
+
+	var results []models.SearchResult
+	for _, find := range []func([]string) ([]models.SearchResult, error){d.searchNews, d.searchDocuments, d.searchFolders} {
+		found, err := find(filter.Terms)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		results = append(results, found...)
+	}
+
+	selected := map[string]bool{}
+	for _, t := range filter.Types {
+		selected[t] = true
+	}
+	page := []models.SearchResult{}
+	for _, result := range results {
+		counts[result.Type]++
+		if len(selected) == 0 || selected[result.Type] {
+			page = append(page, result)
+		}
+	}
+
+	typeOrder := map[string]int{}
+	for i, t := range models.SearchTypes {
+		typeOrder[t] = i
+	}
+	sort.SliceStable(page, func(i, j int) bool {
+		a, b := page[i], page[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.After(b.Date)
+		}
+		return typeOrder[a.Type] < typeOrder[b.Type]
+	})
+
+	total := len(page)
+	if filter.Offset > total {
+		filter.Offset = total
+	}
+	page = page[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(page) {
+		page = page[:filter.Limit]
+	}
+	return page, total, counts, nil
+}
+
+// newSearchResult scores a match: a word found in the title counts 10, one
+// in the body (text or description) bodyWeight, and a title containing all
+// terms gets 5 more. ok is false unless every term is in the title or body.
+func newSearchResult(resultType string, id int, title, body string, bodyWeight int, terms []string) (result models.SearchResult, ok bool) {
+	if !search.ContainsAll(terms, title, body) {
+		return result, false
+	}
+
+	bodyMatches := search.Count(body, terms)
+	if bodyMatches > maxBodyMatches {
+		bodyMatches = maxBodyMatches
+	}
+	score := 10*search.Count(title, terms) + bodyWeight*bodyMatches
+	if search.ContainsAll(terms, title) {
+		score += 5
+	}
+
+	return models.SearchResult{
+		Type:      resultType,
+		TypeLabel: models.SearchTypeLabels[resultType],
+		ID:        id,
+		Title:     title,
+		TitleHTML: search.Snippet(title, terms, 0),
+		Snippet:   search.Snippet(body, terms, siteSearchSnippetWidth),
+		Score:     float64(score),
+	}, true
+}
+
+// searchText is the folded text of a row that site search looks in
+func searchText(parts ...string) string {
+	return search.Fold(strings.Join(parts, "\n"))
+}
+
+func newsSearchText(a models.NewsArticle) string {
+	text := a.Content
+	if a.ContentHTML != "" {
+		text = markup.PlainText(a.ContentHTML)
+	}
+	return searchText(a.Title, text)
+}
+
+// searchTextCondition selects the rows whose search_text contains every
+// term. Terms are letters and digits only, so they need no LIKE escaping.
+func searchTextCondition(column string, terms []string) (string, []interface{}) {
+	conditions := make([]string, len(terms))
+	args := make([]interface{}, len(terms))
+	for i, term := range terms {
+		conditions[i] = column + ` LIKE ?`
+		args[i] = "%" + term + "%"
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (d *Database) searchNews(terms []string) ([]models.SearchResult, error) {
+	match, args := searchTextCondition("n.search_text", terms)
+	rows, err := d.db.Query(`SELECT `+newsColumns+` FROM news n WHERE `+publicNewsCondition+` AND `+match+`
+              ORDER BY COALESCE(n.publish_at, n.created_at) DESC, n.id DESC LIMIT `+strconv.Itoa(maxSearchCandidates), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search news query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		a, err := scanNews(rows)
+		if err != nil {
+			log.Printf("Error scanning news: %v", err)
+			continue
+		}
+		text := a.Content
+		if a.ContentHTML != "" {
+			text = markup.PlainText(a.ContentHTML)
+		}
+
+		result, ok := newSearchResult(models.SearchTypeNews, a.ID, a.Title, text, 1, terms)
+		if !ok {
+			continue
+		}
+		result.URL = a.URL
+		if result.URL == "" {
+			result.URL = "/news_article.html?id=" + strconv.Itoa(a.ID)
+		}
+		result.Date = a.CreatedAt
+		if a.PublishAt != nil {
+			result.Date = *a.PublishAt
+		}
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating news: %v", err)
+	}
+	return results, nil
+}
+
+func (d *Database) searchDocuments(terms []string) ([]models.SearchResult, error) {
+	match, args := searchTextCondition("d.search_text", terms)
+	rows, err := d.db.Query(`SELECT `+documentColumns+` FROM documents d LEFT JOIN folders f ON d.folder_id = f.id
+              WHERE `+publicDocument+` AND `+match+`
+              ORDER BY d.updated_at DESC, d.id DESC LIMIT `+strconv.Itoa(maxSearchCandidates), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search documents query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			log.Printf("Error scanning document: %v", err)
+			continue
+		}
+
+		result, ok := newSearchResult(models.SearchTypeDocument, doc.ID, doc.Title, doc.Description, 4, terms)
+		if !ok {
+			continue
+		}
+		result.URL = fmt.Sprintf("/api/documents/%d/download", doc.ID)
+		result.Date = doc.UpdatedAt
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %v", err)
+	}
+	return results, nil
+}
+
+func (d *Database) searchFolders(terms []string) ([]models.SearchResult, error) {
+	match, args := searchTextCondition("search_text", terms)
+	rows, err := d.db.Query(`SELECT `+folderColumns(true)+` FROM folders WHERE `+publicFolder+` AND `+match+`
+              ORDER BY created_at DESC, id DESC LIMIT `+strconv.Itoa(maxSearchCandidates), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search folders query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			log.Printf("Error scanning folder: %v", err)
+			continue
+		}
+
+		result, ok := newSearchResult(models.SearchTypeFolder, folder.ID, folder.Name, folder.Description, 4, terms)
+		if !ok {
+			continue
+		}
+		result.URL = fmt.Sprintf("/documents.html?folder=%d", folder.ID)
+		result.Date = folder.CreatedAt
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating folders: %v", err)
+	}
+	return results, nil
+}
+
+// backfillSearchText заполняет search_text у новостей, документов и папок,
+// созданных до появления этой колонки
+func (d *Database) backfillSearchText() error {
+	rows, err := d.db.Query(`SELECT ` + newsColumns + ` FROM news n WHERE n.search_text = ''`)
+	if err != nil {
+		return fmt.Errorf("error reading news without search text: %v", err)
+	}
+	texts := map[int]string{}
+	for rows.Next() {
+		a, err := scanNews(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning news without search text: %v", err)
+		}
+		texts[a.ID] = newsSearchText(a)
+	}
+	rows.Close()
+	for id, text := range texts {
+		if _, err := d.db.Exec(`UPDATE news SET search_text = ? WHERE id = ?`, text, id); err != nil {
+			return fmt.Errorf("error setting search text of news %d: %v", id, err)
+		}
+	}
+
+	for _, table := range []string{"documents", "folders"} {
+		titleColumn := "title"
+		if table == "folders" {
+			titleColumn = "name"
+		}
+		rows, err := d.db.Query(`SELECT id, ` + titleColumn + `, COALESCE(description, '') FROM ` + table + ` WHERE search_text = ''`)
+		if err != nil {
+			return fmt.Errorf("error reading %s without search text: %v", table, err)
+		}
+		texts := map[int]string{}
+		for rows.Next() {
+			var id int
+			var title, description string
+			if err := rows.Scan(&id, &title, &description); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning %s without search text: %v", table, err)
+			}
+			texts[id] = searchText(title, description)
+		}
+		rows.Close()
+		for id, text := range texts {
+			if _, err := d.db.Exec(`UPDATE `+table+` SET search_text = ? WHERE id = ?`, text, id); err != nil {
+				return fmt.Errorf("error setting search text of %s %d: %v", table, id, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"school-website/internal/models"
+	"school-website/internal/slug"
+)
+
+// --- Tag Operations ---
+
+// GetTags lists all tags by name with the number of articles for each.
+// With publicOnly only published articles are counted and unused tags are
+// left out, which is what the public filter chips need.
+func (d *Database) GetTags(publicOnly bool) ([]models.Tag, error) {
+	query := `SELECT t.id, t.name, t.slug, COUNT(n.id)
+			  FROM tags t
+			  LEFT JOIN news_tags nt ON nt.tag_id = t.id
+			  LEFT JOIN news n ON n.id = nt.news_id`
+	if publicOnly {
+		query += ` AND ` + publicNewsCondition
+	}
+	query += ` GROUP BY t.id`
+	if publicOnly {
+		query += ` HAVING COUNT(n.id) > 0`
+	}
+	query += ` ORDER BY t.name COLLATE NOCASE`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("GetTags query failed: %v", err)
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Slug, &t.Count); err != nil {
+			log.Printf("Error scanning tag: %v", err)
+			continue
+		}
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %v", err)
+	}
+
+	return tags, nil
+}
+
+func (d *Database) GetTag(id string) (models.Tag, error) {
+	var t models.Tag
+	err := d.db.QueryRow(`SELECT t.id, t.name, t.slug, (SELECT COUNT(*) FROM news_tags WHERE tag_id = t.id)
+			  FROM tags t WHERE t.id = ?`, id).Scan(&t.ID, &t.Name, &t.Slug, &t.Count)
+	if err == sql.ErrNoRows {
+		return t, fmt.Errorf("tag with ID %s not found", id)
+	}
+	if err != nil {
+		return t, fmt.Errorf("error getting tag with ID %s: %v", id, err)
+	}
+	return t, nil
+}
+
+// tagNameKey is the case-folded name used to find and deduplicate tags
+func tagNameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// FindTagByName returns the tag with the given name (case-insensitive)
+func (d *Database) FindTagByName(name string) (models.Tag, error) {
+	var t models.Tag
+	err := d.db.QueryRow(`SELECT id, name, slug FROM tags WHERE name_key = ?`, tagNameKey(name)).Scan(&t.ID, &t.Name, &t.Slug)
+	if err == sql.ErrNoRows {
+		return t, fmt.Errorf("tag %s not found", name)
+	}
+	if err != nil {
+		return t, fmt.Errorf("error getting tag %s: %v", name, err)
+	}
+	return t, nil
+}
+
+// TagNameTaken reports whether another tag (not excludeID) already has the name
+func (d *Database) TagNameTaken(name string, excludeID int) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM tags WHERE name_key = ? AND id != ?`, tagNameKey(name), excludeID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking tag name %s: %v", name, err)
+	}
+	return count > 0, nil
+}
+
+// TagSlugTaken reports whether another tag (not excludeID) already uses the slug
+func (d *Database) TagSlugTaken(tagSlug string, excludeID int) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM tags WHERE slug = ? AND id != ?`, tagSlug, excludeID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking tag slug %s: %v", tagSlug, err)
+	}
+	return count > 0, nil
+}
+
+// UniqueTagSlug generates a slug from the tag name that no other tag uses
+func (d *Database) UniqueTagSlug(name string, excludeID int) (string, error) {
+	base := slug.Make(name)
+	if base == "" {
+		base = "tag"
+	}
+
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := d.TagSlugTaken(candidate, excludeID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (d *Database) CreateTag(name, tagSlug string) (int64, error) {
+	result, err := d.db.Exec(`INSERT INTO tags(name, name_key, slug) VALUES (?, ?, ?)`, name, tagNameKey(name), tagSlug)
+	if err != nil {
+		return 0, fmt.Errorf("error creating tag %s: %v", name, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	log.Printf("Tag created: %s (ID: %d)", name, id)
+	return id, nil
+}
+
+func (d *Database) UpdateTag(id, name, tagSlug string) error {
+	result, err := d.db.Exec(`UPDATE tags SET name = ?, name_key = ?, slug = ? WHERE id = ?`, name, tagNameKey(name), tagSlug, id)
+	if err != nil {
+		return fmt.Errorf("error updating tag with ID %s: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %s not found", id)
+	}
+
+	return nil
+}
+
+// DeleteTag removes the tag and detaches it from all articles
+func (d *Database) DeleteTag(id string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM news_tags WHERE tag_id = ?`, id); err != nil {
+		return fmt.Errorf("error detaching tag with ID %s: %v", id, err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting tag with ID %s: %v", id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %s not found", id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing tag deletion: %v", err)
+	}
+
+	log.Printf("Tag with ID %s successfully deleted", id)
+	return nil
+}
+
+// SetNewsTags replaces the tags of an article
+func (d *Database) SetNewsTags(newsID int, tagIDs []int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM news_tags WHERE news_id = ?`, newsID); err != nil {
+		return fmt.Errorf("error clearing tags of news %d: %v", newsID, err)
+	}
+	for _, tagID := range tagIDs {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO news_tags(news_id, tag_id) VALUES (?, ?)`, newsID, tagID); err != nil {
+			return fmt.Errorf("error tagging news %d: %v", newsID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing news tags: %v", err)
+	}
+	return nil
+}
+
+// GetNewsTags returns the tags of a single article
+func (d *Database) GetNewsTags(newsID int) ([]models.Tag, error) {
+	tags, err := d.getTagsForNews([]int{newsID})
+	if err != nil {
+		return nil, err
+	}
+	if tags[newsID] == nil {
+		return []models.Tag{}, nil
+	}
+	return tags[newsID], nil
+}
+
+// attachNewsTags loads the tags of all given articles with a single query
+func (d *Database) attachNewsTags(articles []models.NewsArticle) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+
+	tags, err := d.getTagsForNews(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range articles {
+		articles[i].Tags = tags[articles[i].ID]
+		if articles[i].Tags == nil {
+			articles[i].Tags = []models.Tag{}
+		}
+	}
+	return nil
+}
+
+func (d *Database) getTagsForNews(newsIDs []int) (map[int][]models.Tag, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(newsIDs)), ",")
+	args := make([]interface{}, len(newsIDs))
+	for i, id := range newsIDs {
+		args[i] = id
+	}
+
+	rows, err := d.db.Query(`SELECT nt.news_id, t.id, t.name, t.slug FROM news_tags nt
+			  JOIN tags t ON t.id = nt.tag_id
+			  WHERE nt.news_id IN (`+placeholders+`) ORDER BY t.name COLLATE NOCASE`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting news tags: %v", err)
+	}
+	defer rows.Close()
+
+	result := map[int][]models.Tag{}
+	for rows.Next() {
+		var newsID int
+		var t models.Tag
+		if err := rows.Scan(&newsID, &t.ID, &t.Name, &t.Slug); err != nil {
+			log.Printf("Error scanning news tag: %v", err)
+			continue
+		}
+		result[newsID] = append(result[newsID], t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating news tags: %v", err)
+	}
+
+	return result, nil
+}
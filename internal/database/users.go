@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"school-website/internal/models"
+)
+
+// --- User Operations ---
+
+const userColumns = `id, username, COALESCE(full_name, '') as full_name, role, password_hash,
+			  active, last_login_at, created_at, updated_at`
+
+func scanUser(scanner interface{ Scan(...interface{}) error }) (models.User, error) {
+	var u models.User
+	var lastLogin sql.NullTime
+	err := scanner.Scan(&u.ID, &u.Username, &u.FullName, &u.Role, &u.PasswordHash,
+		&u.Active, &lastLogin, &u.CreatedAt, &u.UpdatedAt)
+	if lastLogin.Valid {
+		u.LastLoginAt = &lastLogin.Time
+	}
+	return u, err
+}
+
+func (d *Database) CountUsers() (int, error) {
+	var count int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting users: %v", err)
+	}
+	return count, nil
+}
+
+func (d *Database) CreateUser(username, fullName, role, passwordHash string) (int64, error) {
+	insertSQL := `INSERT INTO users(username, full_name, role, password_hash, active, created_at, updated_at)
+                  VALUES (?, ?, ?, ?, 1, ?, ?)`
+	statement, err := d.db.Prepare(insertSQL)
+	if err != nil {
+		return 0, fmt.Errorf("error preparing CreateUser statement: %v", err)
+	}
+	defer statement.Close()
+
+	now := time.Now()
+	result, err := statement.Exec(username, fullName, role, passwordHash, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("error creating user: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting last insert id: %v", err)
+	}
+
+	log.Printf("User successfully created: %s (ID: %d)", username, id)
+	return id, nil
+}
+
+func (d *Database) GetUsers() ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY username ASC`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsers query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			log.Printf("Error scanning user: %v", err)
+			continue
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %v", err)
+	}
+
+	return users, nil
+}
+
+func (d *Database) GetUser(id int) (models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	u, err := scanUser(d.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return u, fmt.Errorf("user with ID %d not found", id)
+		}
+		return u, fmt.Errorf("error getting user with ID %d: %v", id, err)
+	}
+
+	return u, nil
+}
+
+func (d *Database) GetUserByUsername(username string) (models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ?`
+
+	u, err := scanUser(d.db.QueryRow(query, username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return u, fmt.Errorf("user %s not found", username)
+		}
+		return u, fmt.Errorf("error getting user %s: %v", username, err)
+	}
+
+	return u, nil
+}
+
+func (d *Database) SetUserActive(id int, active bool) error {
+	result, err := d.db.Exec(`UPDATE users SET active = ?, updated_at = ? WHERE id = ?`, active, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating user with ID %d: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+
+	log.Printf("User with ID %d active=%t", id, active)
+	return nil
+}
+
+func (d *Database) UpdateUserRole(id int, role string) error {
+	result, err := d.db.Exec(`UPDATE users SET role = ?, updated_at = ? WHERE id = ?`, role, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating role for user with ID %d: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+
+	log.Printf("User with ID %d role=%s", id, role)
+	return nil
+}
+
+func (d *Database) UpdateUserPassword(id int, passwordHash string) error {
+	result, err := d.db.Exec(`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error updating password for user with ID %d: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", id)
+	}
+
+	log.Printf("Password updated for user with ID %d", id)
+	return nil
+}
+
+func (d *Database) TouchUserLogin(id int) error {
+	if _, err := d.db.Exec(`UPDATE users SET last_login_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return fmt.Errorf("error updating last login for user with ID %d: %v", id, err)
+	}
+	return nil
+}
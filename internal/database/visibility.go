@@ -0,0 +1,18 @@
+package database
+
+// --- Visibility ---
+
+// staffFolderIDs selects the staff-only folders together with every folder
+// inside them: a folder is hidden from visitors if any folder above it is.
+const staffFolderIDs = `WITH RECURSIVE staff(id) AS (
+                SELECT sf.id FROM folders sf WHERE sf.visibility = 'staff'
+                UNION
+                SELECT cf.id FROM folders cf JOIN staff s ON cf.parent_id = s.id
+            ) SELECT id FROM staff`
+
+// publicDocument is true for a document (alias d) visitors may see: the
+// document is public and isn't inside a staff-only folder
+const publicDocument = `(d.visibility = 'public' AND (d.folder_id IS NULL OR d.folder_id NOT IN (` + staffFolderIDs + `)))`
+
+// publicFolder is true for a folder (table folders) visitors may see
+const publicFolder = `(folders.id NOT IN (` + staffFolderIDs + `))`
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"school-website/internal/models"
+	"school-website/internal/search"
+)
+
+// visibilityTree is a folder tree with a staff-only folder in the middle:
+//
+//	Открытая (public)
+//	├── Совет (staff)
+//	│   └── Протоколы (public, hidden by its parent)
+//	└── Положения (public)
+type visibilityTree struct {
+	open, council, minutes, rules int
+}
+
+func newVisibilityTree(t *testing.T, db *Database) visibilityTree {
+	t.Helper()
+	folder := func(name, visibility string, parentID int) int {
+		id, err := db.CreateFolder(name, "", "folder", visibility, parentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+	var tree visibilityTree
+	tree.open = folder("Открытая", models.VisibilityPublic, 0)
+	tree.council = folder("Совет", models.VisibilityStaff, tree.open)
+	tree.minutes = folder("Протоколы", models.VisibilityPublic, tree.council)
+	tree.rules = folder("Положения", models.VisibilityPublic, tree.open)
+
+	document := func(title, visibility string, folderID int) {
+		_, err := db.SaveDocument(models.Document{
+			Title: title, FileName: "x.pdf", FilePath: "documents/x.pdf", FileSize: 100,
+			FileType: "application/pdf", FolderID: folderID, Visibility: visibility,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	document("Устав школы", models.VisibilityPublic, tree.open)
+	document("Черновик устава", models.VisibilityStaff, tree.open)
+	document("Состав совета", models.VisibilityPublic, tree.council)
+	document("Протокол заседания", models.VisibilityPublic, tree.minutes)
+	document("Положение о приеме", models.VisibilityPublic, tree.rules)
+	document("Расписание звонков", models.VisibilityPublic, 0)
+	return tree
+}
+
+func documentTitles(docs []models.Document) string {
+	var titles []string
+	for _, doc := range docs {
+		titles = append(titles, doc.Title)
+	}
+	sort.Strings(titles)
+	return strings.Join(titles, ", ")
+}
+
+func folderNames(folders []models.Folder) string {
+	var names []string
+	for _, folder := range folders {
+		names = append(names, folder.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func TestStaffFolderHidesEverythingBelow(t *testing.T) {
+	db := newTestDatabase(t)
+	tree := newVisibilityTree(t, db)
+
+	docs, err := db.GetDocuments(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := documentTitles(docs), "Положение о приеме, Расписание звонков, Устав школы"; got != want {
+		t.Errorf("public documents = %s, want %s", got, want)
+	}
+
+	all, err := db.GetDocuments(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := map[string]bool{"Устав школы": true, "Положение о приеме": true, "Расписание звонков": true}
+	for _, doc := range all {
+		if doc.Public != public[doc.Title] {
+			t.Errorf("document %q: Public = %v", doc.Title, doc.Public)
+		}
+		single, err := db.GetDocument(strconv.Itoa(doc.ID))
+		if err != nil || single.Public != doc.Public {
+			t.Errorf("GetDocument(%q): Public = %v, %v; the list says %v", doc.Title, single.Public, err, doc.Public)
+		}
+	}
+
+	// The public folder inside the staff one is hidden with it
+	for _, id := range []int{tree.council, tree.minutes} {
+		if _, err := db.GetPublicFolder(strconv.Itoa(id)); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("GetPublicFolder(%d) error = %v, want not found", id, err)
+		}
+		if docs, err := db.GetDocumentsByFolder(strconv.Itoa(id), true); err != nil || len(docs) != 0 {
+			t.Errorf("public documents of hidden folder %d: %s, %v", id, documentTitles(docs), err)
+		}
+	}
+
+	publicFolders, err := db.GetFolders(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range publicFolders {
+		if folder.ID == tree.council || folder.ID == tree.minutes || !folder.Public {
+			t.Errorf("GetFolders(true) lists %q (public %v)", folder.Name, folder.Public)
+		}
+	}
+
+	subfolders, err := db.GetSubfolders(strconv.Itoa(tree.open), true)
+	if err != nil || folderNames(subfolders) != "Положения" {
+		t.Errorf("public subfolders = %s, %v; want Положения", folderNames(subfolders), err)
+	}
+	subfolders, err = db.GetSubfolders(strconv.Itoa(tree.open), false)
+	if err != nil || folderNames(subfolders) != "Положения, Совет" {
+		t.Errorf("all subfolders = %s, %v; want Положения, Совет", folderNames(subfolders), err)
+	}
+
+	// Visitors count only the documents they can open
+	open, err := db.GetPublicFolder(strconv.Itoa(tree.open))
+	if err != nil || open.DocumentCount != 1 || open.TotalSize != 100 {
+		t.Errorf("public folder: %d documents of %d bytes, %v; want 1 of 100", open.DocumentCount, open.TotalSize, err)
+	}
+	open, err = db.GetFolder(strconv.Itoa(tree.open))
+	if err != nil || open.DocumentCount != 2 {
+		t.Errorf("folder for staff: %d documents, %v; want 2", open.DocumentCount, err)
+	}
+}
+
+func TestSearchHonoursStaffFolders(t *testing.T) {
+	db := newTestDatabase(t)
+	newVisibilityTree(t, db)
+
+	find := func(query string, publicOnly bool) string {
+		hits, total, err := db.SearchDocuments(search.Terms(query), publicOnly, 20, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != len(hits) {
+			t.Errorf("search %q: total %d, %d hits", query, total, len(hits))
+		}
+		docs := make([]models.Document, len(hits))
+		for i, hit := range hits {
+			docs[i] = hit.Document
+		}
+		return documentTitles(docs)
+	}
+
+	if got := find("протокол", true); got != "" {
+		t.Errorf("visitors find %s in a staff folder", got)
+	}
+	if got := find("протокол", false); got != "Протокол заседания" {
+		t.Errorf("staff search = %q, want Протокол заседания", got)
+	}
+	if got, want := find("устав", true), "Устав школы"; got != want {
+		t.Errorf("visitors search = %q, want %q", got, want)
+	}
+}
+
+func TestVisibilityFollowsFolderChanges(t *testing.T) {
+	db := newTestDatabase(t)
+	tree := newVisibilityTree(t, db)
+
+	publicTitles := func() string {
+		docs, err := db.GetDocuments(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return documentTitles(docs)
+	}
+
+	// Moving the minutes out of the council makes them public
+	if err := db.MoveFolder(strconv.Itoa(tree.minutes), tree.open); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := publicTitles(), "Положение о приеме, Протокол заседания, Расписание звонков, Устав школы"; got != want {
+		t.Errorf("after move: %s, want %s", got, want)
+	}
+
+	// Hiding the top folder hides the whole tree, documents at the top level stay
+	open, err := db.GetFolder(strconv.Itoa(tree.open))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open.Visibility = models.VisibilityStaff
+	if err := db.UpdateFolder(open); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := publicTitles(), "Расписание звонков"; got != want {
+		t.Errorf("after hiding the top folder: %s, want %s", got, want)
+	}
+}
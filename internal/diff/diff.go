@@ -0,0 +1,127 @@
+// Package diff computes line-based differences between two texts.
+package diff
+
+import "strings"
+
+// Line operations
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxCells limits the size of the LCS table; larger inputs are reported as
+// a full replacement of the differing middle part.
+const maxCells = 4_000_000
+
+// Line is one line of a diff
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines returns the line-by-line difference that turns a into b
+func Lines(a, b string) []Line {
+	return compute(splitLines(a), splitLines(b))
+}
+
+// Changed reports whether the diff contains any insertions or deletions
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func compute(a, b []string) []Line {
+	var result []Line
+
+	// Common prefix and suffix don't need the LCS table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, text := range a[:prefix] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxCells {
+		for _, text := range midA {
+			result = append(result, Line{Op: Delete, Text: text})
+		}
+		for _, text := range midB {
+			result = append(result, Line{Op: Insert, Text: text})
+		}
+	} else {
+		result = append(result, lcs(midA, midB)...)
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+
+	return result
+}
+
+// lcs diffs a and b using the longest common subsequence of their lines
+func lcs(a, b []string) []Line {
+	n, m := len(a), len(b)
+
+	// table[i][j] is the LCS length of a[i:] and b[j:]
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			result = append(result, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: Insert, Text: b[j]})
+	}
+
+	return result
+}
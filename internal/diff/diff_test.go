@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// format writes a diff compactly, one "=", "-" or "+" prefixed line each
+func format(lines []Line) string {
+	prefix := map[string]string{Equal: "=", Delete: "-", Insert: "+"}
+	var parts []string
+	for _, l := range lines {
+		parts = append(parts, prefix[l.Op]+l.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// apply rebuilds one side of a diff: the old text without insertions or
+// the new one without deletions
+func apply(lines []Line, skip string) string {
+	var kept []string
+	for _, l := range lines {
+		if l.Op != skip {
+			kept = append(kept, l.Text)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"same text", "a\nb", "a\nb", "=a =b"},
+		{"both empty", "", "", ""},
+		{"from empty", "", "a\nb", "+a +b"},
+		{"to empty", "a\nb", "", "-a -b"},
+		{"changed line", "a\nb\nc", "a\nB\nc", "=a -b +B =c"},
+		{"inserted line", "a\nc", "a\nb\nc", "=a +b =c"},
+		{"deleted line", "a\nb\nc", "a\nc", "=a -b =c"},
+		{"moved line", "a\nb\nc", "b\nc\na", "-a =b =c +a"},
+		{"windows line endings", "a\r\nb\r\n", "a\nb", "=a =b"},
+		{"trailing newline ignored", "a\nb\n", "a\nb", "=a =b"},
+		{"blank lines count", "a\n\nb", "a\nb", "=a - =b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if format(got) != tt.want {
+				t.Errorf("Lines(%q, %q) = %s, want %s", tt.a, tt.b, format(got), tt.want)
+			}
+			if Changed(got) == splitLinesEqual(tt.a, tt.b) {
+				t.Errorf("Changed = %v for %q and %q", Changed(got), tt.a, tt.b)
+			}
+		})
+	}
+}
+
+// splitLinesEqual reports whether a and b have the same lines
+func splitLinesEqual(a, b string) bool {
+	return strings.Join(splitLines(a), "\n") == strings.Join(splitLines(b), "\n")
+}
+
+// Whatever the input, dropping insertions gives the old text back, dropping
+// deletions gives the new one, and the common lines are as many as possible
+func TestLinesRebuildsBothSides(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"Новость", "", "Фото", "Текст", "Итоги"}
+	randomText := func() string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		lines := Lines(a, b)
+		if got := apply(lines, Insert); got != strings.Join(splitLines(a), "\n") {
+			t.Fatalf("Lines(%q, %q): old side rebuilt as %q", a, b, got)
+		}
+		if got := apply(lines, Delete); got != strings.Join(splitLines(b), "\n") {
+			t.Fatalf("Lines(%q, %q): new side rebuilt as %q", a, b, got)
+		}
+		if got, want := countEqual(lines), lcsLength(splitLines(a), splitLines(b)); got != want {
+			t.Fatalf("Lines(%q, %q) keeps %d common lines, the longest common subsequence has %d", a, b, got, want)
+		}
+	}
+}
+
+func countEqual(lines []Line) int {
+	n := 0
+	for _, l := range lines {
+		if l.Op == Equal {
+			n++
+		}
+	}
+	return n
+}
+
+// lcsLength is a plain recursive LCS, fine for the short texts above
+func lcsLength(a, b []string) int {
+	memo := map[[2]int]int{}
+	var f func(i, j int) int
+	f = func(i, j int) int {
+		if i == len(a) || j == len(b) {
+			return 0
+		}
+		if v, ok := memo[[2]int{i, j}]; ok {
+			return v
+		}
+		v := f(i+1, j)
+		if w := f(i, j+1); w > v {
+			v = w
+		}
+		if a[i] == b[j] {
+			if w := f(i+1, j+1) + 1; w > v {
+				v = w
+			}
+		}
+		memo[[2]int{i, j}] = v
+		return v
+	}
+	return f(0, 0)
+}
+
+func TestLinesLargeInput(t *testing.T) {
+	// Above maxCells the differing middle is replaced as a whole, but the
+	// common start and end are still matched
+	var a, b []string
+	a = append(a, "начало")
+	b = append(b, "начало")
+	for i := 0; i < 2500; i++ {
+		a = append(a, fmt.Sprintf("старая %d", i))
+		b = append(b, fmt.Sprintf("новая %d", i))
+	}
+	a = append(a, "конец")
+	b = append(b, "конец")
+
+	lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if len(lines) != 5002 {
+		t.Fatalf("got %d lines, want 5002", len(lines))
+	}
+	if lines[0] != (Line{Equal, "начало"}) || lines[len(lines)-1] != (Line{Equal, "конец"}) {
+		t.Errorf("common start and end not kept: %v ... %v", lines[0], lines[len(lines)-1])
+	}
+	if lines[1] != (Line{Delete, "старая 0"}) || lines[2501] != (Line{Insert, "новая 0"}) {
+		t.Errorf("middle = %v, %v; want all deletions, then all insertions", lines[1], lines[2501])
+	}
+}
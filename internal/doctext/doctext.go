@@ -0,0 +1,295 @@
+// Package doctext extracts the plain text of uploaded documents (PDF, DOCX,
+// ODT and TXT) for the search index.
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// MaxLength is the number of bytes of text kept per document
+	MaxLength = 1 << 20
+
+	// maxXMLSize limits how much of a compressed XML part is read, so that
+	// a small archive can't unpack into gigabytes
+	maxXMLSize = 64 << 20
+)
+
+const (
+	docxType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	odtType  = "application/vnd.oasis.opendocument.text"
+)
+
+// ErrUnsupported is returned for file types text is not extracted from
+var ErrUnsupported = errors.New("text extraction is not supported for this file type")
+
+// Supported reports whether text can be extracted from files of a MIME type
+// as detected by filetype.Detect
+func Supported(mimeType string) bool {
+	switch mimeType {
+	case "application/pdf", docxType, odtType, "text/plain":
+		return true
+	}
+	return false
+}
+
+// Extract returns the text of a document, with runs of spaces collapsed and
+// cut to MaxLength. Scanned PDFs and empty documents give an empty string.
+func Extract(r io.ReaderAt, size int64, mimeType string) (text string, err error) {
+	// The PDF parser panics on some damaged files
+	defer func() {
+		if p := recover(); p != nil {
+			text, err = "", fmt.Errorf("malformed document: %v", p)
+		}
+	}()
+
+	switch mimeType {
+	case "application/pdf":
+		text, err = pdfText(r, size)
+	case docxType:
+		text, err = zipXMLText(r, size, "word/document.xml", docxText)
+	case odtType:
+		text, err = zipXMLText(r, size, "content.xml", odtText)
+	case "text/plain":
+		text, err = plainText(r, size)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+	return normalize(text), nil
+}
+
+func pdfText(r io.ReaderAt, size int64) (string, error) {
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pdf: %v", err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage() && b.Len() < MaxLength; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to read page %d of pdf: %v", i, err)
+		}
+		b.WriteString(text)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// xmlFormat describes where the text is in the XML of an office document
+type xmlFormat struct {
+	textElement string            // only character data inside it is text; "" for all
+	paragraphs  map[string]bool   // elements followed by a line break
+	inline      map[string]string // empty elements standing for a character
+}
+
+var docxText = xmlFormat{
+	textElement: "t",
+	paragraphs:  map[string]bool{"p": true},
+	inline:      map[string]string{"tab": "\t", "br": "\n", "cr": "\n"},
+}
+
+var odtText = xmlFormat{
+	paragraphs: map[string]bool{"p": true, "h": true},
+	inline:     map[string]string{"s": " ", "tab": "\t", "line-break": "\n"},
+}
+
+func zipXMLText(r io.ReaderAt, size int64, part string, format xmlFormat) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to read archive: %v", err)
+	}
+
+	for _, f := range archive.File {
+		if f.Name != part {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %v", part, err)
+		}
+		defer rc.Close()
+		return xmlText(io.LimitReader(rc, maxXMLSize), format)
+	}
+	return "", fmt.Errorf("%s not found in archive", part)
+}
+
+func xmlText(r io.Reader, format xmlFormat) (string, error) {
+	decoder := xml.NewDecoder(r)
+	var b strings.Builder
+	depth := 0 // nesting inside the text element
+
+	for b.Len() < MaxLength {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == format.textElement {
+				depth++
+			}
+			if s, ok := format.inline[t.Name.Local]; ok {
+				b.WriteString(strings.Repeat(s, repeatCount(t)))
+			}
+		case xml.EndElement:
+			if t.Name.Local == format.textElement && depth > 0 {
+				depth--
+			}
+			if format.paragraphs[t.Name.Local] {
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if format.textElement == "" || depth > 0 {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// repeatCount reads the number of spaces of an ODT <text:s text:c="3"/>
+func repeatCount(element xml.StartElement) int {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "c" {
+			if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 && n <= 100 {
+				return n
+			}
+		}
+	}
+	return 1
+}
+
+// plainText decodes a text file. UTF-8 and UTF-16 with a byte order mark are
+// recognized; anything that isn't valid UTF-8 is read as Windows-1251, the
+// usual encoding of Russian text files saved on Windows.
+func plainText(r io.ReaderAt, size int64) (string, error) {
+	if size > 4*MaxLength {
+		size = 4 * MaxLength
+	}
+	data := make([]byte, size)
+	n, err := r.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	data = data[:n]
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false), nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true), nil
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+	// A file cut at MaxLength may end in the middle of a character
+	if trimmed := trimIncompleteRune(data); utf8.Valid(trimmed) {
+		return string(trimmed), nil
+	}
+	return decodeWindows1251(data), nil
+}
+
+func trimIncompleteRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// windows1251 maps the bytes 0x80–0xBF of Windows-1251; 0xC0–0xFF are А–я
+var windows1251 = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', '�', '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	' ', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '­', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+func decodeWindows1251(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data) * 2)
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xC0:
+			b.WriteRune(windows1251[c-0x80])
+		default:
+			b.WriteRune(rune(c-0xC0) + 'А')
+		}
+	}
+	return b.String()
+}
+
+// normalize collapses spaces within lines, drops empty lines and control
+// characters, and cuts the text to MaxLength on a character boundary
+func normalize(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u00ad", "").Replace(text)
+	var lines []string
+	length := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.FieldsFunc(line, isSpace), " ")
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		length += len(line) + 1
+		if length > MaxLength {
+			break
+		}
+	}
+
+	text = strings.Join(lines, "\n")
+	if len(text) > MaxLength {
+		text = text[:MaxLength]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return text
+}
+
+func isSpace(r rune) bool {
+	return r <= ' ' || r == ' ' || r == '�' || (r >= 0x7F && r < 0xA0)
+}
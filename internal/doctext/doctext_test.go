@@ -0,0 +1,193 @@
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// zipArchive packs files into an in-memory zip, like DOCX and ODT are
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// onePagePDF builds a minimal PDF with one line of text per string
+func onePagePDF(lines ...string) []byte {
+	var content strings.Builder
+	content.WriteString("BT /F1 12 Tf 72 720 Td 14 TL\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+	}
+	content.WriteString("ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func extract(t *testing.T, data []byte, mimeType string) string {
+	t.Helper()
+	text, err := Extract(bytes.NewReader(data), int64(len(data)), mimeType)
+	if err != nil {
+		t.Fatalf("Extract(%s) error = %v", mimeType, err)
+	}
+	return text
+}
+
+func TestExtractDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+  <w:p><w:r><w:t>Учебный</w:t></w:r><w:r><w:t xml:space="preserve"> план</w:t></w:r></w:p>
+  <w:p><w:r><w:t>Предмет</w:t><w:tab/><w:t>Часы</w:t><w:br/><w:t>Алгебра</w:t></w:r></w:p>
+  <w:p><w:r><w:instrText> PAGE \* MERGEFORMAT </w:instrText></w:r><w:r><w:delText>удалено</w:delText></w:r></w:p>
+  <w:p><w:r><w:t>Итого:   34   часа</w:t></w:r></w:p>
+</w:body>
+</w:document>`
+	data := zipArchive(t, map[string]string{
+		"[Content_Types].xml": `<Types/>`,
+		"word/document.xml":   document,
+		"word/styles.xml":     `<w:styles><w:t>стиль</w:t></w:styles>`,
+	})
+
+	got := extract(t, data, docxType)
+	want := "Учебный план\nПредмет Часы\nАлгебра\nИтого: 34 часа"
+	if got != want {
+		t.Errorf("DOCX text = %q, want %q", got, want)
+	}
+}
+
+func TestExtractODT(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:text>
+  <text:h text:outline-level="1">Отчет о работе</text:h>
+  <text:p>Проведено<text:s text:c="3"/>мероприятий:<text:tab/><text:span>12</text:span></text:p>
+  <text:p/>
+  <text:p>Первая строка<text:line-break/>вторая строка</text:p>
+</office:text></office:body>
+</office:document-content>`
+	data := zipArchive(t, map[string]string{
+		"mimetype":    odtType,
+		"content.xml": content,
+	})
+
+	got := extract(t, data, odtType)
+	want := "Отчет о работе\nПроведено мероприятий: 12\nПервая строка\nвторая строка"
+	if got != want {
+		t.Errorf("ODT text = %q, want %q", got, want)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	got := extract(t, onePagePDF("School timetable", "Lessons start at 8:30"), "application/pdf")
+	for _, want := range []string{"School timetable", "Lessons start at 8:30"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PDF text %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	utf16LE := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune("Меню на неделю")) {
+		utf16LE = append(utf16LE, byte(u), byte(u>>8))
+	}
+	utf16BE := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune("Меню на неделю")) {
+		utf16BE = append(utf16BE, byte(u>>8), byte(u))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"utf-8", []byte("Меню\r\n\r\n  на   неделю\t\n"), "Меню\nна неделю"},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, "Меню на неделю"...), "Меню на неделю"},
+		{"utf-16le", utf16LE, "Меню на неделю"},
+		{"utf-16be", utf16BE, "Меню на неделю"},
+		// "Меню на неделю №1" saved in Windows-1251
+		{"windows-1251", []byte{0xCC, 0xE5, 0xED, 0xFE, ' ', 0xED, 0xE0, ' ', 0xED, 0xE5, 0xE4, 0xE5, 0xEB, 0xFE, ' ', 0xB9, '1'}, "Меню на неделю №1"},
+		{"control characters", []byte("Меню\x00\x07 на\x1b неделю"), "Меню на неделю"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extract(t, tt.data, "text/plain"); got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractCutsLongText(t *testing.T) {
+	data := []byte(strings.Repeat("ы", MaxLength)) // two bytes per letter
+	got := extract(t, data, "text/plain")
+
+	if len(got) > MaxLength || !utf8.ValidString(got) {
+		t.Errorf("text of %d bytes, valid UTF-8 %v; want at most %d", len(got), utf8.ValidString(got), MaxLength)
+	}
+	if len(got) < MaxLength-utf8.UTFMax {
+		t.Errorf("text cut to %d bytes, want close to %d", len(got), MaxLength)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	if _, err := Extract(bytes.NewReader(nil), 0, "image/png"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("image: error = %v, want ErrUnsupported", err)
+	}
+
+	broken := []byte("PK\x03\x04 not really a zip")
+	if _, err := Extract(bytes.NewReader(broken), int64(len(broken)), docxType); err == nil {
+		t.Error("damaged DOCX: no error")
+	}
+
+	noContent := zipArchive(t, map[string]string{"mimetype": odtType})
+	if _, err := Extract(bytes.NewReader(noContent), int64(len(noContent)), odtType); err == nil {
+		t.Error("ODT without content.xml: no error")
+	}
+
+	damaged := onePagePDF("text")[:200]
+	if _, err := Extract(bytes.NewReader(damaged), int64(len(damaged)), "application/pdf"); err == nil {
+		t.Error("truncated PDF: no error")
+	}
+}
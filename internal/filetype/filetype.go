@@ -0,0 +1,178 @@
+// Package filetype detects the type of uploaded files from their content
+// rather than from the file name or the Content-Type sent by the browser.
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// oleSignature starts legacy Microsoft Office files (.doc, .xls, .ppt)
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// oleTypes maps extensions of legacy Office files to their MIME types. The
+// container format is the same for all of them, so the extension is only
+// used to tell them apart once the signature has matched.
+var oleTypes = map[string]string{
+	".doc": "application/msword",
+	".xls": "application/vnd.ms-excel",
+	".ppt": "application/vnd.ms-powerpoint",
+}
+
+// ooxmlTypes maps the top-level directory of an Office Open XML archive to its MIME type
+var ooxmlTypes = map[string]string{
+	"word/": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xl/":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ppt/":  "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// NotAllowedError reports an upload whose content is not of an allowed type
+type NotAllowedError struct {
+	Name    string   // original file name
+	Type    string   // detected MIME type
+	Allowed []string // the allow-list the file was checked against
+}
+
+func (e *NotAllowedError) Error() string {
+	return fmt.Sprintf("file type %s is not allowed", e.Type)
+}
+
+// Detect returns the MIME type of a file from its first bytes, without
+// parameters such as charset. ZIP archives are inspected further to
+// recognize DOCX, XLSX, PPTX and OpenDocument files.
+func Detect(r io.ReaderAt, size int64, name string) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	head = head[:n]
+
+	detected := http.DetectContentType(head)
+	if mediaType, _, err := mime.ParseMediaType(detected); err == nil {
+		detected = mediaType
+	}
+
+	switch {
+	case detected == "application/zip":
+		return zipType(r, size), nil
+	case detected == "application/octet-stream" && bytes.HasPrefix(head, oleSignature):
+		if t, ok := oleTypes[strings.ToLower(filepath.Ext(name))]; ok {
+			return t, nil
+		}
+	}
+	return detected, nil
+}
+
+// Check detects the type of a file and returns a *NotAllowedError if it is
+// not in the allow-list
+func Check(r io.ReaderAt, size int64, name string, allowed []string) (string, error) {
+	detected, err := Detect(r, size, name)
+	if err != nil {
+		return "", err
+	}
+	if !Allowed(detected, allowed) {
+		return detected, &NotAllowedError{Name: name, Type: detected, Allowed: allowed}
+	}
+	return detected, nil
+}
+
+// Allowed reports whether mimeType matches the allow-list. Entries may be
+// exact types ("application/pdf") or wildcards ("image/*").
+func Allowed(mimeType string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == mimeType {
+			return true
+		}
+		if prefix := strings.TrimSuffix(a, "*"); prefix != a && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// extensions are the file extensions used for stored files of common types
+var extensions = map[string]string{
+	"image/jpeg":         ".jpg",
+	"image/png":          ".png",
+	"image/gif":          ".gif",
+	"image/webp":         ".webp",
+	"application/pdf":    ".pdf",
+	"application/zip":    ".zip",
+	"text/plain":         ".txt",
+	"application/msword": ".doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.ms-excel": ".xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.ms-powerpoint":                                             ".ppt",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.oasis.opendocument.text":                                   ".odt",
+	"application/vnd.oasis.opendocument.spreadsheet":                            ".ods",
+	"application/vnd.oasis.opendocument.presentation":                           ".odp",
+}
+
+// Extension returns the file extension (with the dot) for a MIME type, or
+// an empty string if the type is unknown
+func Extension(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	if ext, ok := extensions[mimeType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// zipType tells Office Open XML and OpenDocument files apart from plain ZIP archives
+func zipType(r io.ReaderAt, size int64) string {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "application/zip"
+	}
+
+	for _, f := range archive.File {
+		// OpenDocument stores its type uncompressed in the "mimetype" entry
+		if f.Name == "mimetype" {
+			rc, err := f.Open()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(io.LimitReader(rc, 100))
+			rc.Close()
+			if t := strings.TrimSpace(string(data)); strings.HasPrefix(t, "application/vnd.oasis.opendocument.") {
+				return t
+			}
+		}
+	}
+
+	hasContentTypes := false
+	for _, f := range archive.File {
+		if f.Name == "[Content_Types].xml" {
+			hasContentTypes = true
+			break
+		}
+	}
+	if hasContentTypes {
+		for _, f := range archive.File {
+			for dir, t := range ooxmlTypes {
+				if strings.HasPrefix(f.Name, dir) {
+					return t
+				}
+			}
+		}
+	}
+
+	return "application/zip"
+}
@@ -0,0 +1,99 @@
+package filetype
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// zipFile builds a ZIP archive in memory with the given entries, in order
+func zipFile(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range entries {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := "<xml/>"
+		if name == "mimetype" {
+			content = "application/vnd.oasis.opendocument.text"
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	ole := append(append([]byte{}, oleSignature...), make([]byte, 504)...)
+
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+		want    string
+	}{
+		{"pdf", "a.pdf", []byte("%PDF-1.4\n%âãÏÓ\n"), "application/pdf"},
+		{"png", "a.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"text without charset", "a.txt", []byte("Расписание звонков"), "text/plain"},
+		{"pdf named docx", "a.docx", []byte("%PDF-1.4\n"), "application/pdf"},
+		{"docx", "a.docx", zipFile(t, "[Content_Types].xml", "_rels/.rels", "word/document.xml"),
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"xlsx", "a.zip", zipFile(t, "[Content_Types].xml", "xl/workbook.xml"),
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"pptx", "a.pptx", zipFile(t, "[Content_Types].xml", "ppt/presentation.xml"),
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		{"office dirs without content types", "a.docx", zipFile(t, "word/document.xml"), "application/zip"},
+		{"odt", "a.odt", zipFile(t, "mimetype", "content.xml"), "application/vnd.oasis.opendocument.text"},
+		{"plain zip", "a.zip", zipFile(t, "readme.txt"), "application/zip"},
+		{"doc", "a.doc", ole, "application/msword"},
+		{"xls upper case", "A.XLS", ole, "application/vnd.ms-excel"},
+		{"ppt", "a.ppt", ole, "application/vnd.ms-powerpoint"},
+		{"ole with other extension", "a.bin", ole, "application/octet-stream"},
+		{"empty", "a.txt", nil, "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(bytes.NewReader(tt.content), int64(len(tt.content)), tt.file)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZipTypeBrokenArchive(t *testing.T) {
+	data := zipFile(t, "[Content_Types].xml", "word/document.xml")
+	data = data[:len(data)-10] // cut the end of central directory
+	if got := zipType(bytes.NewReader(data), int64(len(data))); got != "application/zip" {
+		t.Errorf("zipType() = %q, want application/zip", got)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		allowed  []string
+		want     bool
+	}{
+		{"application/pdf", []string{"application/pdf"}, true},
+		{"image/webp", []string{"application/pdf", "image/*"}, true},
+		{"text/plain", []string{"image/*"}, false},
+		{"application/pdf", nil, false},
+		{"application/pdfx", []string{"application/pdf"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Allowed(tt.mimeType, tt.allowed); got != tt.want {
+			t.Errorf("Allowed(%q, %v) = %v, want %v", tt.mimeType, tt.allowed, got, tt.want)
+		}
+	}
+}
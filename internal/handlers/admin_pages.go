@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+)
+
+type AdminPageHandler struct {
+	templatesDir string
+}
+
+func NewAdminPageHandler(templatesDir string) *AdminPageHandler {
+	return &AdminPageHandler{templatesDir: templatesDir}
+}
+
+// adminPageData is passed to every admin template so pages can show the
+// current user and hide actions the user's role does not allow.
+type adminPageData struct {
+	User *models.User
+	Can  map[string]bool
+}
+
+// Page renders the given admin template for the logged in user.
+func (h *AdminPageHandler) Page(file string) http.HandlerFunc {
+	filePath := filepath.Join(h.templatesDir, file)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := middleware.CurrentUser(r)
+		if user == nil {
+			http.Redirect(w, r, "/admin/login.html", http.StatusFound)
+			return
+		}
+
+		// Templates are parsed on every request so they can be edited without a restart
+		tmpl, err := template.ParseFiles(filePath)
+		if err != nil {
+			log.Printf("Error parsing template %s: %v", filePath, err)
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+			return
+		}
+
+		data := adminPageData{User: user, Can: user.Permissions()}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmpl.Execute(w, data); err != nil {
+			log.Printf("Error rendering template %s: %v", filePath, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"school-website/internal/models"
+	"school-website/internal/services"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// GetAuditLog lists audit entries. Supported query parameters: user_id,
+// username, action, entity_type, entity_id, from, to (YYYY-MM-DD or
+// RFC 3339; a plain "to" date includes the whole day), limit and offset.
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Username:   query.Get("username"),
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+	}
+
+	var err error
+	if v := query.Get("user_id"); v != "" {
+		if filter.UserID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.From, err = parseDateParam(query.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseDateParam(query.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, filter.Offset, err = parsePagination(query, defaultAuditLimit, maxAuditLimit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.service.GetEntries(filter)
+	if err != nil {
+		log.Printf("Error getting audit log: %v", err)
+		http.Error(w, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":  entries,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
@@ -5,21 +5,21 @@ import (
 	"log"
 	"net/http"
 
-	"school-website/internal/config"
 	"school-website/internal/models"
+	"school-website/internal/services"
 
 	"github.com/gorilla/sessions"
 )
 
 type AuthHandler struct {
-	store  *sessions.CookieStore
-	config *config.Config
+	store       *sessions.CookieStore
+	userService *services.UserService
 }
 
-func NewAuthHandler(store *sessions.CookieStore, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(store *sessions.CookieStore, userService *services.UserService) *AuthHandler {
 	return &AuthHandler{
-		store:  store,
-		config: cfg,
+		store:       store,
+		userService: userService,
 	}
 }
 
@@ -35,8 +35,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Login attempt for user: %s", creds.Username)
 
 	// Check credentials
-	if creds.Username != h.config.AdminUsername || creds.Password != h.config.AdminPassword {
-		log.Printf("Invalid credentials for user: %s", creds.Username)
+	user, err := h.userService.Authenticate(creds.Username, creds.Password)
+	if err != nil {
+		log.Printf("Login failed for user %s: %v", creds.Username, err)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -44,6 +45,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Create session
 	session, _ := h.store.Get(r, "session-name")
 	session.Values["authenticated"] = true
+	session.Values["user_id"] = user.ID
 	err = session.Save(r, w)
 	if err != nil {
 		log.Printf("Error saving session: %v", err)
@@ -51,7 +53,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("User %s successfully authenticated", creds.Username)
+	log.Printf("User %s successfully authenticated", user.Username)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Login successful"})
 }
@@ -61,6 +63,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 
 	// Reset authentication flag
 	session.Values["authenticated"] = false
+	delete(session.Values, "user_id")
 	session.Options.MaxAge = -1 // Delete cookie
 
 	err := session.Save(r, w)
@@ -2,23 +2,34 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"school-website/internal/middleware"
+	"school-website/internal/models"
 	"school-website/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	maxDocumentTitleLength = 255
+	maxMoveDocuments       = 500 // documents in one bulk move request
+)
+
 type DocumentHandler struct {
 	service *services.DocumentService
+	audit   *services.AuditService
 }
 
-func NewDocumentHandler(service *services.DocumentService) *DocumentHandler {
-	return &DocumentHandler{service: service}
+func NewDocumentHandler(service *services.DocumentService, audit *services.AuditService) *DocumentHandler {
+	return &DocumentHandler{service: service, audit: audit}
 }
 
 func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
@@ -51,6 +62,14 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 	description := r.FormValue("description")
 	category := r.FormValue("category")
 	folderIDStr := r.FormValue("folder_id")
+	visibility := r.FormValue("visibility")
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+	if !models.IsValidVisibility(visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
 
 	// Get all files from form
 	files := r.MultipartForm.File["document"]
@@ -63,7 +82,20 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 	// Parse folder ID
 	var folderID int
 	if folderIDStr != "" {
-		folderID, _ = strconv.Atoi(folderIDStr)
+		var err error
+		if folderID, err = strconv.Atoi(folderIDStr); err != nil || folderID < 0 {
+			http.Error(w, "Invalid folder ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.service.CheckFolder(folderID); err != nil {
+		if errors.Is(err, services.ErrFolderNotFound) {
+			http.Error(w, "Folder not found", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error checking folder %d: %v", folderID, err)
+		http.Error(w, "Failed to upload document", http.StatusInternalServerError)
+		return
 	}
 
 	// Handle single file (backward compatibility)
@@ -88,13 +120,18 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 		}
 		defer file.Close()
 
-		doc, err := h.service.UploadDocument(fileTitle, description, category, folderID, file, files[0])
+		doc, err := h.service.UploadDocument(fileTitle, description, category, visibility, folderID, file, files[0], middleware.CurrentUser(r))
+		if writeRejectedUpload(w, err) {
+			return
+		}
 		if err != nil {
 			log.Printf("Error uploading document: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to upload document: %v", err), http.StatusInternalServerError)
 			return
 		}
 
+		h.audit.Record(r, models.AuditCreate, models.EntityDocument, doc.ID, nil, doc)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(doc)
@@ -103,7 +140,10 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Handle multiple files - use filename as title for each if title is empty
-	documents, errors := h.service.UploadMultipleDocuments(title, description, category, folderID, files)
+	documents, errors := h.service.UploadMultipleDocuments(title, description, category, visibility, folderID, files, middleware.CurrentUser(r))
+	for _, doc := range documents {
+		h.audit.Record(r, models.AuditCreate, models.EntityDocument, doc.ID, nil, doc)
+	}
 
 	response := map[string]interface{}{
 		"success":   len(documents),
@@ -123,7 +163,9 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if len(errors) > 0 && len(documents) == 0 {
+	if len(errors) > 0 && len(documents) == 0 && allRejected(errors) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	} else if len(errors) > 0 && len(documents) == 0 {
 		w.WriteHeader(http.StatusInternalServerError)
 	} else if len(errors) > 0 {
 		w.WriteHeader(http.StatusPartialContent) // 206 for partial success
@@ -138,14 +180,15 @@ func (h *DocumentHandler) GetAllDocuments(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 
 	category := r.URL.Query().Get("category")
+	publicOnly := middleware.CurrentUser(r) == nil
 
 	var documents interface{}
 	var err error
 
 	if category != "" {
-		documents, err = h.service.GetDocumentsByCategory(category)
+		documents, err = h.service.GetDocumentsByCategory(category, publicOnly)
 	} else {
-		documents, err = h.service.GetAllDocuments()
+		documents, err = h.service.GetAllDocuments(publicOnly)
 	}
 
 	if err != nil {
@@ -164,7 +207,11 @@ func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	// Staff-only documents don't exist for visitors
 	doc, err := h.service.GetDocument(id)
+	if err == nil && !doc.Public && middleware.CurrentUser(r) == nil {
+		err = fmt.Errorf("document %s is not public", id)
+	}
 	if err != nil {
 		log.Printf("Error getting document: %v", err)
 		http.Error(w, "Document not found", http.StatusNotFound)
@@ -179,20 +226,202 @@ func (h *DocumentHandler) DownloadDocument(w http.ResponseWriter, r *http.Reques
 	id := vars["id"]
 
 	doc, err := h.service.GetDocument(id)
+	if err == nil && !doc.Public && middleware.CurrentUser(r) == nil {
+		err = fmt.Errorf("document %s is not public", id)
+	}
 	if err != nil {
 		log.Printf("Error getting document for download: %v", err)
 		http.Error(w, "Document not found", http.StatusNotFound)
 		return
 	}
 
-	// Set headers for download
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", doc.FileName))
+	file, err := h.service.OpenDocument(doc)
+	if err != nil {
+		log.Printf("Error opening file of document %d: %v", doc.ID, err)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "File not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to read document", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	// Set headers for download. The URL stays the same when a new version is
+	// uploaded, so caches must check back before reusing a copy; shared caches
+	// must not keep staff-only files at all.
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", doc.OriginalName))
 	w.Header().Set("Content-Type", doc.FileType)
-	w.Header().Set("Content-Length", strconv.FormatInt(doc.FileSize, 10))
+	if doc.Public {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	serveObject(w, r, doc.FileName, file)
+	log.Printf("Document downloaded: %s (ID: %d)", doc.OriginalName, doc.ID)
+}
 
-	// Serve the file
-	http.ServeFile(w, r, doc.FilePath)
-	log.Printf("Document downloaded: %s (ID: %d)", doc.FileName, doc.ID)
+// documentInput is the editable metadata of a document. With PATCH, fields
+// left out of the request keep their values; PUT replaces all of them.
+type documentInput struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Category    *string `json:"category"`
+	FolderID    *int    `json:"folder_id"`
+	Visibility  *string `json:"visibility"`
+}
+
+// UpdateDocument changes the title, description, category, folder and
+// visibility of a document (PUT or PATCH)
+func (h *DocumentHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	var input documentInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existingDoc, err := h.service.GetDocument(id)
+	if err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	doc := *existingDoc
+	if r.Method == http.MethodPut {
+		if input.Title == nil {
+			http.Error(w, "Title is required", http.StatusBadRequest)
+			return
+		}
+		doc.Description, doc.Category, doc.FolderID = "", "", 0
+		doc.Visibility = models.VisibilityPublic
+	}
+	if input.Title != nil {
+		doc.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		doc.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Category != nil {
+		doc.Category = strings.TrimSpace(*input.Category)
+	}
+	if input.FolderID != nil {
+		doc.FolderID = *input.FolderID
+	}
+	if input.Visibility != nil {
+		doc.Visibility = *input.Visibility
+	}
+
+	if doc.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(doc.Title)) > maxDocumentTitleLength {
+		http.Error(w, "Title is too long", http.StatusBadRequest)
+		return
+	}
+	if doc.FolderID < 0 {
+		http.Error(w, "Invalid folder ID", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidVisibility(doc.Visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.UpdateDocument(&doc)
+	if errors.Is(err, services.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating document %s: %v", id, err)
+		http.Error(w, "Failed to update document", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r, models.AuditUpdate, models.EntityDocument, updated.ID, existingDoc, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// MoveDocuments puts several documents into one folder. Expects
+// {"document_ids": [...], "folder_id": N}; folder_id 0 takes the documents
+// out of any folder. Nothing is moved if one of the documents doesn't exist.
+func (h *DocumentHandler) MoveDocuments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input struct {
+		DocumentIDs []int `json:"document_ids"`
+		FolderID    *int  `json:"folder_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.FolderID == nil || *input.FolderID < 0 {
+		http.Error(w, "folder_id is required", http.StatusBadRequest)
+		return
+	}
+	folderID := *input.FolderID
+
+	var ids []int
+	seen := map[int]bool{}
+	for _, id := range input.DocumentIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "No documents given", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > maxMoveDocuments {
+		http.Error(w, fmt.Sprintf("At most %d documents can be moved at once", maxMoveDocuments), http.StatusBadRequest)
+		return
+	}
+
+	before := make([]*models.Document, 0, len(ids))
+	for _, id := range ids {
+		doc, err := h.service.GetDocument(strconv.Itoa(id))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Document %d not found", id), http.StatusNotFound)
+			return
+		}
+		before = append(before, doc)
+	}
+
+	err := h.service.MoveDocuments(ids, folderID)
+	if errors.Is(err, services.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error moving documents: %v", err)
+		http.Error(w, "Failed to move documents", http.StatusInternalServerError)
+		return
+	}
+
+	documents := make([]*models.Document, 0, len(before))
+	for _, existingDoc := range before {
+		doc, err := h.service.GetDocument(strconv.Itoa(existingDoc.ID))
+		if err != nil {
+			log.Printf("Error getting moved document %d: %v", existingDoc.ID, err)
+			continue
+		}
+		h.audit.Record(r, models.AuditUpdate, models.EntityDocument, doc.ID, existingDoc, doc)
+		documents = append(documents, doc)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"moved":     len(ids),
+		"folder_id": folderID,
+		"documents": documents,
+	})
 }
 
 func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
@@ -207,12 +436,21 @@ func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	existingDoc, err := h.service.GetDocument(id)
+	if err != nil {
+		log.Printf("Error getting document for deletion: %v", err)
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
 	if err := h.service.DeleteDocument(id); err != nil {
 		log.Printf("Error deleting document: %v", err)
 		http.Error(w, "Failed to delete document", http.StatusInternalServerError)
 		return
 	}
 
+	h.audit.Record(r, models.AuditDelete, models.EntityDocument, id, existingDoc, nil)
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "Document deleted successfully"}`))
 	log.Printf("Document deleted successfully: ID %s", id)
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"school-website/internal/middleware"
+	"school-website/internal/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// SearchDocuments finds documents by the words of their title, description
+// and text. Expects the query in "q"; returns a page of hits, the most
+// relevant first, with the matched words marked in title_html and snippet.
+// Visitors only find public documents.
+func (h *DocumentHandler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		http.Error(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(q)) > search.MaxQueryLength {
+		http.Error(w, "Search query is too long", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePagination(query, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hits, total, err := h.service.SearchDocuments(q, middleware.CurrentUser(r) == nil, limit, offset)
+	if err != nil {
+		log.Printf("Error searching documents for %q: %v", q, err)
+		http.Error(w, "Failed to search documents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":  q,
+		"items":  hits,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
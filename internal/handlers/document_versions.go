@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxVersionUpload     = 100 << 20 // size of one new document version
+	maxVersionNoteLength = 500
+)
+
+// UploadVersion replaces the file of a document, keeping the previous file
+// as an earlier version. Expects the file in the "document" form field and an
+// optional change description in "note"; returns the updated document.
+func (h *DocumentHandler) UploadVersion(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existingDoc, err := h.service.GetDocument(id)
+	if err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxVersionUpload+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Printf("Error parsing document version upload: %v", err)
+		http.Error(w, "Unable to parse form or upload is too large", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len([]rune(note)) > maxVersionNoteLength {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+
+	files := r.MultipartForm.File["document"]
+	if len(files) != 1 {
+		http.Error(w, "Exactly one file is expected", http.StatusBadRequest)
+		return
+	}
+
+	file, err := files[0].Open()
+	if err != nil {
+		log.Printf("Error opening file: %v", err)
+		http.Error(w, "Failed to open file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	doc, err := h.service.UploadVersion(existingDoc, note, file, files[0], middleware.CurrentUser(r))
+	if writeRejectedUpload(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error uploading version of document %s: %v", id, err)
+		http.Error(w, fmt.Sprintf("Failed to upload document version: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r, models.AuditUpdate, models.EntityDocument, doc.ID, existingDoc, doc)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(doc)
+	log.Printf("Document %d: version %d uploaded", doc.ID, doc.Version)
+}
+
+// GetVersions lists the versions of a document, newest first
+func (h *DocumentHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	if _, err := h.service.GetDocument(id); err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	versions, err := h.service.GetVersions(id)
+	if err != nil {
+		log.Printf("Error getting versions of document %s: %v", id, err)
+		http.Error(w, "Failed to get versions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(versions)
+}
+
+// DownloadVersion sends the file of a particular version of a document
+func (h *DocumentHandler) DownloadVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	number, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		http.Error(w, "Invalid version number", http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.service.GetVersion(vars["id"], number)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Version not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error getting document version: %v", err)
+			http.Error(w, "Failed to get version", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	file, err := h.service.OpenVersion(version)
+	if err != nil {
+		log.Printf("Error opening version %d of document %d: %v", version.Version, version.DocumentID, err)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "File not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to read document", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", version.OriginalName))
+	w.Header().Set("Content-Type", version.FileType)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	serveObject(w, r, version.FileName, file)
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"school-website/internal/config"
+	"school-website/internal/database"
+	"school-website/internal/markup"
+	"school-website/internal/models"
+	"school-website/internal/storage"
+)
+
+const feedSize = 50
+
+type FeedHandler struct {
+	db     *database.Database
+	files  storage.Storage
+	config *config.Config
+}
+
+func NewFeedHandler(db *database.Database, files storage.Storage, cfg *config.Config) *FeedHandler {
+	return &FeedHandler{db: db, files: files, config: cfg}
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// RSS serves the latest published news as an RSS 2.0 feed
+func (h *FeedHandler) RSS(w http.ResponseWriter, r *http.Request) {
+	articles, ok := h.loadArticles(w)
+	if !ok {
+		return
+	}
+
+	base := siteBaseURL(h.config, w, r)
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       h.config.SiteName,
+			Link:        base + "/",
+			Description: "Новости и объявления — " + h.config.SiteName,
+			Language:    "ru",
+			SelfLink:    atomLink{Href: base + "/feed.rss", Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	updated := lastUpdated(articles)
+	if !updated.IsZero() {
+		feed.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+
+	for _, a := range articles {
+		link := base + articleLink(a)
+		item := rssItem{
+			Title:       a.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     articleDate(a).Format(time.RFC1123Z),
+			Description: a.ContentHTML,
+		}
+		// RSS requires the size of an enclosure, so only uploaded images are attached
+		if size, ok := h.imageSize(a.ImageURL); ok {
+			imageURL := absoluteURL(base, a.ImageURL)
+			item.Enclosure = &rssEnclosure{URL: imageURL, Length: size, Type: imageType(imageURL)}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	h.serveFeed(w, r, "application/rss+xml; charset=utf-8", feed)
+}
+
+// Atom serves the latest published news as an Atom 1.0 feed
+func (h *FeedHandler) Atom(w http.ResponseWriter, r *http.Request) {
+	articles, ok := h.loadArticles(w)
+	if !ok {
+		return
+	}
+
+	base := siteBaseURL(h.config, w, r)
+	updated := lastUpdated(articles)
+	if updated.IsZero() {
+		updated = time.Unix(0, 0).UTC()
+	}
+
+	feed := atomFeed{
+		Title:   h.config.SiteName,
+		ID:      base + "/",
+		Updated: updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: base + "/", Rel: "alternate", Type: "text/html"},
+			{Href: base + "/feed.atom", Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	for _, a := range articles {
+		link := base + articleLink(a)
+		date := articleDate(a).Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     a.Title,
+			ID:        link,
+			Links:     []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Published: date,
+			Updated:   date,
+			Summary:   markup.Excerpt(markup.PlainText(a.ContentHTML), 300),
+			Content:   atomText{Type: "html", Value: a.ContentHTML},
+		}
+		if a.ImageURL != "" {
+			imageURL := absoluteURL(base, a.ImageURL)
+			entry.Links = append(entry.Links, atomLink{Href: imageURL, Rel: "enclosure", Type: imageType(imageURL)})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	h.serveFeed(w, r, "application/atom+xml; charset=utf-8", feed)
+}
+
+func (h *FeedHandler) loadArticles(w http.ResponseWriter) ([]models.NewsArticle, bool) {
+	articles, _, err := h.db.GetNews(models.NewsFilter{
+		PublicOnly: true,
+		Sort:       models.NewsSortNewest,
+		Limit:      feedSize,
+	})
+	if err != nil {
+		log.Printf("Error getting news for feed: %v", err)
+		http.Error(w, "Failed to get news", http.StatusInternalServerError)
+		return nil, false
+	}
+	return articles, true
+}
+
+// serveFeed writes the feed with an ETag so feed readers can use conditional
+// requests and get 304 Not Modified. There is no Last-Modified: editing,
+// unpublishing or deleting an article doesn't change any date of the feed,
+// while the ETag is a hash of the whole feed and changes with it.
+func (h *FeedHandler) serveFeed(w http.ResponseWriter, r *http.Request, contentType string, feed interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(feed); err != nil {
+		log.Printf("Error encoding feed: %v", err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.Header().Set("Content-Type", contentType)
+	if h.config.SiteURL != "" {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	} else {
+		// Links were built from the request's Host, so only the reader may keep them
+		w.Header().Set("Cache-Control", "private, max-age=300")
+	}
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+// imageSize returns the size in bytes of an uploaded image, read from the
+// file storage. It reports false for external images and missing files.
+func (h *FeedHandler) imageSize(url string) (int64, bool) {
+	key, ok := storage.KeyFromURL(url)
+	if !ok {
+		return 0, false
+	}
+	file, err := h.files.Open(key)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error opening feed image %s: %v", key, err)
+		}
+		return 0, false
+	}
+	file.Close()
+	return file.Size, file.Size >= 0
+}
+
+func articleLink(a models.NewsArticle) string {
+	if a.URL != "" {
+		return a.URL
+	}
+	return "/news_article.html?id=" + strconv.Itoa(a.ID)
+}
+
+func articleDate(a models.NewsArticle) time.Time {
+	if a.PublishAt != nil {
+		return a.PublishAt.UTC()
+	}
+	return a.CreatedAt.UTC()
+}
+
+func lastUpdated(articles []models.NewsArticle) time.Time {
+	var latest time.Time
+	for _, a := range articles {
+		if d := articleDate(a); d.After(latest) {
+			latest = d
+		}
+	}
+	return latest.Truncate(time.Second)
+}
+
+func imageType(url string) string {
+	ext := strings.ToLower(path.Ext(strings.SplitN(url, "?", 2)[0]))
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"school-website/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// DownloadFolder streams a ZIP archive of all documents in a folder and its
+// sub-folders. Visitors get only public folders and documents; a staff-only
+// folder is not found for them.
+func (h *DocumentHandler) DownloadFolder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	publicOnly := middleware.CurrentUser(r) == nil
+
+	folder, err := h.service.GetFolder(id, publicOnly)
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	entries, err := h.service.FolderArchiveEntries(id, publicOnly)
+	if err != nil {
+		log.Printf("Error listing documents of folder %s for archive: %v", id, err)
+		http.Error(w, "Failed to create archive", http.StatusInternalServerError)
+		return
+	}
+
+	// The size is unknown until the end, so the archive goes out chunked
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", folder.Name+".zip"))
+	if folder.Public {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// Headers are already sent: an error can only cut the archive short
+	if err := h.service.WriteArchive(w, entries); err != nil {
+		log.Printf("Error sending archive of folder %s: %v", id, err)
+		return
+	}
+	log.Printf("Folder downloaded as archive: %s (ID: %d, %d documents)", folder.Name, folder.ID, len(entries))
+}
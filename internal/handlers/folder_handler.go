@@ -2,27 +2,35 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"school-website/internal/database"
+	"school-website/internal/middleware"
 	"school-website/internal/models"
+	"school-website/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
 type FolderHandler struct {
-	db *database.Database
+	db    *database.Database
+	audit *services.AuditService
 }
 
-func NewFolderHandler(db *database.Database) *FolderHandler {
-	return &FolderHandler{db: db}
+func NewFolderHandler(db *database.Database, audit *services.AuditService) *FolderHandler {
+	return &FolderHandler{db: db, audit: audit}
 }
 
+// GetAllFolders lists the folders; visitors see only the public ones
 func (h *FolderHandler) GetAllFolders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 
-	folders, err := h.db.GetFolders()
+	folders, err := h.db.GetFolders(middleware.CurrentUser(r) == nil)
 	if err != nil {
 		http.Error(w, "Failed to get folders", http.StatusInternalServerError)
 		return
@@ -46,6 +54,7 @@ func (h *FolderHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	folder.Name = strings.TrimSpace(folder.Name)
 	if folder.Name == "" {
 		http.Error(w, "Folder name is required", http.StatusBadRequest)
 		return
@@ -54,14 +63,31 @@ func (h *FolderHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
 	if folder.Icon == "" {
 		folder.Icon = "folder"
 	}
+	if folder.Visibility == "" {
+		folder.Visibility = models.VisibilityPublic
+	}
+	if !models.IsValidVisibility(folder.Visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.checkFolderPlacement(folder.ParentID, folder.Name, 0); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
 
-	id, err := h.db.CreateFolder(folder.Name, folder.Description, folder.Icon)
+	id, err := h.db.CreateFolder(folder.Name, folder.Description, folder.Icon, folder.Visibility, folder.ParentID)
 	if err != nil {
 		http.Error(w, "Failed to create folder", http.StatusInternalServerError)
 		return
 	}
 
 	folder.ID = int(id)
+	if created, err := h.db.GetFolder(fmt.Sprint(id)); err == nil {
+		folder = created
+	}
+	h.audit.Record(r, models.AuditCreate, models.EntityFolder, id, nil, folder)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(folder)
 }
@@ -78,27 +104,333 @@ func (h *FolderHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	if err := h.db.DeleteFolder(id); err != nil {
+	existingFolder, err := h.db.GetFolder(id)
+	if err != nil {
+		log.Printf("Error getting folder for deletion: %v", err)
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	// A folder with sub-folders or documents is deleted only when asked for
+	// explicitly, together with everything inside it
+	recursive, _ := strconv.ParseBool(r.URL.Query().Get("recursive"))
+	folders, documents, err := h.db.CountFolderContents(id)
+	if err != nil {
+		log.Printf("Error checking folder contents: %v", err)
+		http.Error(w, "Failed to delete folder", http.StatusInternalServerError)
+		return
+	}
+	if (folders > 0 || documents > 0) && !recursive {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":     "Folder is not empty",
+			"message":   "The folder contains sub-folders or documents; pass recursive=true to delete them too",
+			"folders":   folders,
+			"documents": documents,
+		})
+		return
+	}
+
+	if recursive {
+		deleted, err := h.db.DeleteFolderTree(id)
+		for _, doc := range deleted {
+			h.audit.Record(r, models.AuditDelete, models.EntityDocument, doc.ID, doc, nil)
+		}
+		if err != nil {
+			log.Printf("Error deleting folder tree %s: %v", id, err)
+			http.Error(w, "Failed to delete folder", http.StatusInternalServerError)
+			return
+		}
+	} else if err := h.db.DeleteFolder(id); err != nil {
 		http.Error(w, "Failed to delete folder", http.StatusInternalServerError)
 		return
 	}
 
+	h.audit.Record(r, models.AuditDelete, models.EntityFolder, id, existingFolder, nil)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Folder deleted successfully"})
 }
 
+// GetFolderTree returns all folders as a tree of top-level folders with
+// their sub-folders in "children"; visitors see only the public folders
+func (h *FolderHandler) GetFolderTree(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	folders, err := h.db.GetFolders(middleware.CurrentUser(r) == nil)
+	if err != nil {
+		http.Error(w, "Failed to get folders", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.FolderTree(folders))
+}
+
+// MoveFolder puts a folder inside another one. Expects {"parent_id": N};
+// 0 makes it a top-level folder.
+func (h *FolderHandler) MoveFolder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	var input struct {
+		ParentID *int `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.ParentID == nil {
+		http.Error(w, "parent_id is required", http.StatusBadRequest)
+		return
+	}
+
+	existingFolder, err := h.db.GetFolder(id)
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	// The new parent must not be the folder itself or one of its sub-folders
+	subtree, err := h.db.GetFolderSubtree(existingFolder.ID)
+	if err != nil {
+		log.Printf("Error getting sub-folders of folder %s: %v", id, err)
+		http.Error(w, "Failed to move folder", http.StatusInternalServerError)
+		return
+	}
+	for _, folderID := range subtree {
+		if folderID == *input.ParentID {
+			http.Error(w, "A folder cannot be moved into itself or its sub-folder", http.StatusConflict)
+			return
+		}
+	}
+
+	if status, err := h.checkFolderPlacement(*input.ParentID, existingFolder.Name, existingFolder.ID); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.db.MoveFolder(id, *input.ParentID); err != nil {
+		log.Printf("Error moving folder %s: %v", id, err)
+		http.Error(w, "Failed to move folder", http.StatusInternalServerError)
+		return
+	}
+
+	moved, err := h.db.GetFolder(id)
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	h.audit.Record(r, models.AuditUpdate, models.EntityFolder, id, existingFolder, moved)
+
+	json.NewEncoder(w).Encode(moved)
+}
+
+// UpdateFolder changes the name, description, icon and visibility of a
+// folder. With PATCH, fields left out of the request keep their values.
+func (h *FolderHandler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Icon        *string `json:"icon"`
+		Visibility  *string `json:"visibility"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existingFolder, err := h.db.GetFolder(id)
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	folder := existingFolder
+	if r.Method == http.MethodPut {
+		if input.Name == nil {
+			http.Error(w, "Folder name is required", http.StatusBadRequest)
+			return
+		}
+		folder.Description, folder.Icon = "", ""
+		folder.Visibility = models.VisibilityPublic
+	}
+	if input.Name != nil {
+		folder.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		folder.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Icon != nil {
+		folder.Icon = strings.TrimSpace(*input.Icon)
+	}
+	if input.Visibility != nil {
+		folder.Visibility = *input.Visibility
+	}
+
+	if folder.Name == "" {
+		http.Error(w, "Folder name is required", http.StatusBadRequest)
+		return
+	}
+	if folder.Icon == "" {
+		folder.Icon = "folder"
+	}
+	if !models.IsValidVisibility(folder.Visibility) {
+		http.Error(w, "Invalid visibility", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.checkFolderPlacement(folder.ParentID, folder.Name, folder.ID); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.db.UpdateFolder(folder); err != nil {
+		log.Printf("Error updating folder %s: %v", id, err)
+		http.Error(w, "Failed to update folder", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := h.db.GetFolder(id)
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+	h.audit.Record(r, models.AuditUpdate, models.EntityFolder, id, existingFolder, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// ReorderFolders sets the order of the folders inside one parent. Expects
+// {"parent_id": N, "ids": [...]} listing every folder directly inside N (0:
+// the top-level folders) in the new order.
+func (h *FolderHandler) ReorderFolders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input struct {
+		ParentID int   `json:"parent_id"`
+		IDs      []int `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.ParentID != 0 {
+		if _, err := h.db.GetFolder(strconv.Itoa(input.ParentID)); err != nil {
+			http.Error(w, "Folder not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	before, _ := h.db.GetFolders(false)
+	if err := h.db.ReorderFolders(input.ParentID, input.IDs); err != nil {
+		if strings.Contains(err.Error(), "invalid order") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Printf("Error reordering folders: %v", err)
+			http.Error(w, "Failed to reorder folders", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	folders, err := h.db.GetFolders(false)
+	if err != nil {
+		http.Error(w, "Failed to get folders", http.StatusInternalServerError)
+		return
+	}
+	h.audit.Record(r, models.AuditUpdate, models.EntityFolder, input.ParentID,
+		map[string]interface{}{"order": siblingIDs(before, input.ParentID)},
+		map[string]interface{}{"order": siblingIDs(folders, input.ParentID)})
+
+	json.NewEncoder(w).Encode(folders)
+}
+
+// siblingIDs lists the IDs of the folders directly inside parentID, in order
+func siblingIDs(folders []models.Folder, parentID int) []int {
+	ids := []int{}
+	for _, f := range folders {
+		if f.ParentID == parentID {
+			ids = append(ids, f.ID)
+		}
+	}
+	return ids
+}
+
+// checkFolderPlacement checks that parentID is an existing folder (or 0) and
+// that none of its sub-folders other than excludeID is already called name
+func (h *FolderHandler) checkFolderPlacement(parentID int, name string, excludeID int) (int, error) {
+	if parentID < 0 {
+		return http.StatusBadRequest, fmt.Errorf("invalid parent folder")
+	}
+	if parentID != 0 {
+		if _, err := h.db.GetFolder(strconv.Itoa(parentID)); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("parent folder not found")
+		}
+	}
+
+	taken, err := h.db.FolderNameTaken(parentID, name, excludeID)
+	if err != nil {
+		log.Printf("Error checking folder name: %v", err)
+		return http.StatusInternalServerError, fmt.Errorf("failed to check folder name")
+	}
+	if taken {
+		return http.StatusConflict, fmt.Errorf("a folder with this name already exists here")
+	}
+	return http.StatusOK, nil
+}
+
+// GetFolderDocuments returns the documents directly inside a folder together
+// with the folder itself, its breadcrumbs (from the top-level folder down) and
+// its sub-folders. Visitors get only public folders and documents.
 func (h *FolderHandler) GetFolderDocuments(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	folderID := vars["id"]
+	publicOnly := middleware.CurrentUser(r) == nil
+
+	var folder models.Folder
+	var err error
+	if publicOnly {
+		folder, err = h.db.GetPublicFolder(folderID)
+	} else {
+		folder, err = h.db.GetFolder(folderID)
+	}
+	if err != nil {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
+
+	breadcrumbs, err := h.db.GetFolderPath(folderID)
+	if err != nil {
+		log.Printf("Error getting path of folder %s: %v", folderID, err)
+		http.Error(w, "Failed to get documents", http.StatusInternalServerError)
+		return
+	}
 
-	documents, err := h.db.GetDocumentsByFolder(folderID)
+	subfolders, err := h.db.GetSubfolders(folderID, publicOnly)
 	if err != nil {
+		log.Printf("Error getting sub-folders of folder %s: %v", folderID, err)
 		http.Error(w, "Failed to get documents", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(documents)
+	documents, err := h.db.GetDocumentsByFolder(folderID, publicOnly)
+	if err != nil {
+		http.Error(w, "Failed to get documents", http.StatusInternalServerError)
+		return
+	}
+	if documents == nil {
+		documents = []models.Document{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"folder":      folder,
+		"breadcrumbs": breadcrumbs,
+		"subfolders":  subfolders,
+		"documents":   documents,
+	})
 }
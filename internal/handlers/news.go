@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"school-website/internal/database"
+	"school-website/internal/markup"
+	"school-website/internal/middleware"
+	"school-website/internal/models"
 	"school-website/internal/services"
+	"school-website/internal/slug"
 
 	"github.com/gorilla/mux"
 )
@@ -16,21 +22,65 @@ import (
 type NewsHandler struct {
 	db            *database.Database
 	uploadService *services.FileUploadService
+	audit         *services.AuditService
 }
 
-func NewNewsHandler(db *database.Database, uploadService *services.FileUploadService) *NewsHandler {
+func NewNewsHandler(db *database.Database, uploadService *services.FileUploadService, audit *services.AuditService) *NewsHandler {
 	return &NewsHandler{
 		db:            db,
 		uploadService: uploadService,
+		audit:         audit,
 	}
 }
 
+const (
+	defaultNewsLimit = 20
+	maxNewsLimit     = 100
+)
+
+// GetAllNews lists news articles. Supported query parameters: limit, offset,
+// sort (newest, oldest, title), q (search in title and content), tag (tag
+// slug), from and to (publication date range) and view=list to get excerpts
+// instead of the full content.
 func (h *NewsHandler) GetAllNews(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
-	articles, err := h.db.GetNews()
+	query := r.URL.Query()
+	filter := models.NewsFilter{
+		// Visitors only see published articles; the admin panel sees every state
+		PublicOnly:  middleware.CurrentUser(r) == nil,
+		Query:       strings.TrimSpace(query.Get("q")),
+		Tag:         strings.TrimSpace(query.Get("tag")),
+		Sort:        query.Get("sort"),
+		ExcerptOnly: query.Get("view") == "list",
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.NewsSortNewest
+	case models.NewsSortNewest, models.NewsSortOldest, models.NewsSortTitle:
+	default:
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if filter.From, err = parseDateParam(query.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseDateParam(query.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	if filter.Limit, filter.Offset, err = parsePagination(query, defaultNewsLimit, maxNewsLimit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	articles, total, err := h.db.GetNews(filter)
 	if err != nil {
 		log.Printf("Error getting news: %v", err)
 		http.Error(w, "Failed to get news", http.StatusInternalServerError)
@@ -38,7 +88,12 @@ func (h *NewsHandler) GetAllNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(articles)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":  articles,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
 }
 
 func (h *NewsHandler) GetSingleNews(w http.ResponseWriter, r *http.Request) {
@@ -53,9 +108,15 @@ func (h *NewsHandler) GetSingleNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	article, err := h.db.GetNewsArticle(id)
+	// The key may be either the numeric ID or the article's slug
+	article, err := h.findArticle(id)
 	if err != nil {
-		log.Printf("Error getting news with ID %s: %v", id, err)
+		log.Printf("Error getting news %s: %v", id, err)
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	if middleware.CurrentUser(r) == nil && !article.IsPublic(time.Now()) {
 		http.Error(w, "Article not found", http.StatusNotFound)
 		return
 	}
@@ -93,30 +154,77 @@ func (h *NewsHandler) CreateNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	status, publishAt, err := resolveNewsSchedule(r.FormValue("status"), r.FormValue("publish_at"), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tagNames, err := parseTagNames(r.FormValue("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Content is Markdown; the sanitized HTML is rendered once on save
+	contentHTML, err := markup.Render(content)
+	if err != nil {
+		log.Printf("Error rendering news content: %v", err)
+		http.Error(w, "Failed to render content", http.StatusInternalServerError)
+		return
+	}
+
 	// Priority for uploaded file
-	finalImageURL, err := h.uploadService.HandleFileUpload(r)
+	uploaded, err := h.uploadService.HandleFileUpload(r)
+	if writeRejectedUpload(w, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("Error uploading file: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to upload file: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	finalImageURL, imageVariants := uploaded.URL, uploaded.Variants
 	if finalImageURL == "" {
 		finalImageURL = imageURLFromForm
 		log.Printf("Using URL from form: %s", finalImageURL)
 	}
 
-	err = h.db.SaveNews(title, content, finalImageURL)
+	id, err := h.db.SaveNews(models.NewsArticle{
+		Title:         title,
+		Content:       content,
+		ContentHTML:   contentHTML,
+		ImageURL:      finalImageURL,
+		ImageVariants: imageVariants,
+		Status:        status,
+		PublishAt:     publishAt,
+	})
 	if err != nil {
 		log.Printf("Error saving news to database: %v", err)
 		http.Error(w, "Failed to save news", http.StatusInternalServerError)
 		return
 	}
 
+	if len(tagNames) > 0 {
+		if err := h.setTags(r, int(id), tagNames); err != nil {
+			log.Printf("Error tagging news %d: %v", id, err)
+		}
+	}
+
 	response := map[string]interface{}{
 		"success":   true,
 		"message":   "News successfully created",
+		"id":        id,
 		"image_url": finalImageURL,
+		"status":    status,
+	}
+
+	if created, err := h.db.GetNewsArticle(fmt.Sprint(id)); err == nil {
+		h.recordRevision(r, created, models.AuditCreate, 0)
+		h.audit.Record(r, models.AuditCreate, models.EntityNews, id, nil, created)
+		response["slug"] = created.Slug
+		response["url"] = created.URL
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -154,28 +262,96 @@ func (h *NewsHandler) UpdateNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newImageURL, err := h.uploadService.HandleFileUpload(r)
+	status, publishAt, err := resolveNewsSchedule(r.FormValue("status"), r.FormValue("publish_at"), &existingArticle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Tags are only changed when the form sends the field
+	_, updateTags := r.MultipartForm.Value["tags"]
+	tagNames, err := parseTagNames(r.FormValue("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentHTML, err := markup.Render(content)
+	if err != nil {
+		log.Printf("Error rendering news content: %v", err)
+		http.Error(w, "Failed to render content", http.StatusInternalServerError)
+		return
+	}
+
+	// The slug is kept stable so shared links keep working, unless the editor changes it explicitly
+	newSlug := ""
+	if value := strings.TrimSpace(r.FormValue("slug")); value != "" && value != existingArticle.Slug {
+		newSlug = slug.Make(value)
+		if _, err := strconv.Atoi(newSlug); newSlug == "" || err == nil {
+			http.Error(w, "Invalid slug", http.StatusBadRequest)
+			return
+		}
+		taken, err := h.db.NewsSlugTaken(newSlug, existingArticle.ID)
+		if err != nil {
+			log.Printf("Error checking slug: %v", err)
+			http.Error(w, "Failed to update news", http.StatusInternalServerError)
+			return
+		}
+		if taken {
+			http.Error(w, "Slug is already used by another article", http.StatusConflict)
+			return
+		}
+	}
+
+	uploaded, err := h.uploadService.HandleFileUpload(r)
+	if writeRejectedUpload(w, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("Error uploading file during update: %v", err)
 		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
 		return
 	}
 
-	finalImageURL := existingArticle.ImageURL
+	// Resized variants belong to the uploaded file, a URL typed into the
+	// form has none
+	finalImageURL, imageVariants := existingArticle.ImageURL, existingArticle.ImageVariants
 
-	if newImageURL != "" {
-		finalImageURL = newImageURL
+	if uploaded.URL != "" {
+		finalImageURL, imageVariants = uploaded.URL, uploaded.Variants
 	} else if imageURLFromForm != existingArticle.ImageURL {
-		finalImageURL = imageURLFromForm
+		finalImageURL, imageVariants = imageURLFromForm, nil
 	}
 
-	err = h.db.UpdateNewsArticle(id, title, content, finalImageURL)
+	err = h.db.UpdateNewsArticle(id, models.NewsArticle{
+		Slug:          newSlug,
+		Title:         title,
+		Content:       content,
+		ContentHTML:   contentHTML,
+		ImageURL:      finalImageURL,
+		ImageVariants: imageVariants,
+		Status:        status,
+		PublishAt:     publishAt,
+	})
 	if err != nil {
 		log.Printf("Error updating news: %v", err)
 		http.Error(w, "Failed to update news", http.StatusInternalServerError)
 		return
 	}
 
+	if updateTags {
+		if err := h.setTags(r, existingArticle.ID, tagNames); err != nil {
+			log.Printf("Error tagging news %s: %v", id, err)
+			http.Error(w, "Failed to update tags", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if updated, err := h.db.GetNewsArticle(id); err == nil {
+		h.recordRevision(r, updated, models.AuditUpdate, 0)
+		h.audit.Record(r, models.AuditUpdate, models.EntityNews, id, existingArticle, updated)
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "News updated successfully"})
 }
@@ -206,7 +382,14 @@ func (h *NewsHandler) DeleteNews(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Delete request for news ID: %s", id)
 
-	err := h.db.DeleteNewsArticle(id)
+	existingArticle, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		log.Printf("Article with ID %s not found: %v", id, err)
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	err = h.db.DeleteNewsArticle(id)
 	if err != nil {
 		log.Printf("Error deleting news with ID %s: %v", id, err)
 		if strings.Contains(err.Error(), "не найдена") {
@@ -218,6 +401,7 @@ func (h *NewsHandler) DeleteNews(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("News with ID %s successfully deleted", id)
+	h.audit.Record(r, models.AuditDelete, models.EntityNews, id, existingArticle, nil)
 
 	response := map[string]interface{}{
 		"success": true,
@@ -228,3 +412,142 @@ func (h *NewsHandler) DeleteNews(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// setTags replaces the tags of an article, creating tags that don't exist yet
+func (h *NewsHandler) setTags(r *http.Request, newsID int, names []string) error {
+	tagIDs := make([]int, 0, len(names))
+	for _, name := range names {
+		tag, err := h.db.FindTagByName(name)
+		if err != nil {
+			if !strings.Contains(err.Error(), "not found") {
+				return err
+			}
+			tagSlug, err := h.db.UniqueTagSlug(name, 0)
+			if err != nil {
+				return err
+			}
+			id, err := h.db.CreateTag(name, tagSlug)
+			if err != nil {
+				return err
+			}
+			tag = models.Tag{ID: int(id), Name: name, Slug: tagSlug}
+			h.audit.Record(r, models.AuditCreate, models.EntityTag, id, nil, tag)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	return h.db.SetNewsTags(newsID, tagIDs)
+}
+
+// findArticle looks an article up by its numeric ID or by its slug
+func (h *NewsHandler) findArticle(key string) (models.NewsArticle, error) {
+	if _, err := strconv.Atoi(key); err == nil {
+		return h.db.GetNewsArticle(key)
+	}
+	return h.db.GetNewsArticleBySlug(key)
+}
+
+// UpdateNewsStatus changes only the status and publish time of an article,
+// e.g. to publish a draft or unpublish an old announcement without deleting it.
+func (h *NewsHandler) UpdateNewsStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	var input struct {
+		Status    string `json:"status"`
+		PublishAt string `json:"publish_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existingArticle, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		log.Printf("Article with ID %s not found: %v", id, err)
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	status, publishAt, err := resolveNewsSchedule(input.Status, input.PublishAt, &existingArticle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.SetNewsStatus(id, status, publishAt); err != nil {
+		log.Printf("Error updating news status: %v", err)
+		http.Error(w, "Failed to update news status", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+	h.recordRevision(r, updated, models.AuditUpdate, 0)
+	h.audit.Record(r, models.AuditUpdate, models.EntityNews, id, existingArticle, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// resolveNewsSchedule works out the status and publish time of an article
+// from the submitted form values. Empty values keep the existing article's
+// settings (or mean "publish now" for a new article). A published article
+// with a publish time in the future becomes scheduled.
+func resolveNewsSchedule(statusValue, publishAtValue string, existing *models.NewsArticle) (string, *time.Time, error) {
+	status := strings.TrimSpace(statusValue)
+	if status == "" {
+		status = models.NewsPublished
+		if existing != nil {
+			status = existing.Status
+		}
+	}
+	if !models.IsValidNewsStatus(status) {
+		return "", nil, fmt.Errorf("invalid status %q", status)
+	}
+
+	var publishAt *time.Time
+	if existing != nil {
+		publishAt = existing.PublishAt
+	}
+	if value := strings.TrimSpace(publishAtValue); value != "" {
+		t, err := parsePublishAt(value)
+		if err != nil {
+			return "", nil, err
+		}
+		publishAt = &t
+	}
+
+	now := time.Now()
+	switch status {
+	case models.NewsScheduled:
+		if publishAt == nil {
+			return "", nil, fmt.Errorf("publish_at is required for scheduled news")
+		}
+	case models.NewsPublished:
+		if publishAt == nil || (existing != nil && !existing.IsPublic(now) && strings.TrimSpace(publishAtValue) == "") {
+			// Publishing without an explicit time means "right now"
+			publishAt = &now
+		} else if publishAt.After(now) {
+			status = models.NewsScheduled
+		}
+	}
+
+	return status, publishAt, nil
+}
+
+// parsePublishAt accepts RFC 3339 timestamps as well as the value of an
+// HTML datetime-local input, which is interpreted in the server's time zone.
+func parsePublishAt(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid publish_at %q", value)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"school-website/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxGalleryUpload = 200 << 20 // total size of one gallery upload request
+	maxGalleryFiles  = 50        // images per upload request
+	maxCaptionLength = 500
+)
+
+// UploadNewsImages adds the images sent in the "images" form field (several
+// files at once) to the end of the article's gallery.
+func (h *NewsHandler) UploadNewsImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	article, ok := h.galleryArticle(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGalleryUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		log.Printf("Error parsing gallery upload: %v", err)
+		http.Error(w, "Unable to parse form or upload is too large", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		http.Error(w, "No images uploaded", http.StatusBadRequest)
+		return
+	}
+	if len(files) > maxGalleryFiles {
+		http.Error(w, fmt.Sprintf("Too many images, at most %d per upload", maxGalleryFiles), http.StatusBadRequest)
+		return
+	}
+
+	uploaded, err := h.uploadService.HandleMultipleUploads(r, "images")
+	if writeRejectedUpload(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error uploading gallery images: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to upload images: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	newImages := make([]models.NewsImage, len(uploaded))
+	for i, img := range uploaded {
+		newImages[i] = models.NewsImage{URL: img.URL, Variants: img.Variants}
+	}
+
+	images, err := h.db.AddNewsImages(article.ID, newImages)
+	if err != nil {
+		log.Printf("Error saving gallery images: %v", err)
+		h.uploadService.RemoveImages(uploaded)
+		http.Error(w, "Failed to save images", http.StatusInternalServerError)
+		return
+	}
+
+	for _, img := range images {
+		h.audit.Record(r, models.AuditCreate, models.EntityNewsImage, img.ID, nil, img)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(images)
+}
+
+// ReorderNewsImages sets the gallery order from {"ids": [...]}, which must
+// list every image of the article.
+func (h *NewsHandler) ReorderNewsImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	article, ok := h.galleryArticle(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		IDs []int `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, _ := h.db.GetNewsImages(article.ID)
+	if err := h.db.ReorderNewsImages(article.ID, input.IDs); err != nil {
+		if strings.Contains(err.Error(), "invalid order") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Printf("Error reordering gallery: %v", err)
+			http.Error(w, "Failed to reorder images", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	images, err := h.db.GetNewsImages(article.ID)
+	if err != nil {
+		http.Error(w, "Failed to get images", http.StatusInternalServerError)
+		return
+	}
+	h.audit.Record(r, models.AuditUpdate, models.EntityNews, article.ID,
+		map[string]interface{}{"images": before}, map[string]interface{}{"images": images})
+
+	json.NewEncoder(w).Encode(images)
+}
+
+// UpdateNewsImage changes the caption of a gallery image
+func (h *NewsHandler) UpdateNewsImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	article, ok := h.galleryArticle(w, r)
+	if !ok {
+		return
+	}
+	imageID := mux.Vars(r)["imageId"]
+
+	var input struct {
+		Caption string `json:"caption"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caption := strings.TrimSpace(input.Caption)
+	if len([]rune(caption)) > maxCaptionLength {
+		http.Error(w, "Caption is too long", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.db.GetNewsImage(article.ID, imageID)
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.UpdateNewsImageCaption(article.ID, imageID, caption); err != nil {
+		log.Printf("Error updating gallery image: %v", err)
+		http.Error(w, "Failed to update image", http.StatusInternalServerError)
+		return
+	}
+
+	updated := existing
+	updated.Caption = caption
+	h.audit.Record(r, models.AuditUpdate, models.EntityNewsImage, existing.ID, existing, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteNewsImage removes a single image from the gallery and deletes its file
+func (h *NewsHandler) DeleteNewsImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	article, ok := h.galleryArticle(w, r)
+	if !ok {
+		return
+	}
+	imageID := mux.Vars(r)["imageId"]
+
+	existing, err := h.db.GetNewsImage(article.ID, imageID)
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.DeleteNewsImage(article.ID, imageID); err != nil {
+		log.Printf("Error deleting gallery image: %v", err)
+		http.Error(w, "Failed to delete image", http.StatusInternalServerError)
+		return
+	}
+	h.audit.Record(r, models.AuditDelete, models.EntityNewsImage, existing.ID, existing, nil)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Image successfully deleted",
+		"id":      existing.ID,
+	})
+}
+
+// galleryArticle loads the article named by the {id} route variable
+func (h *NewsHandler) galleryArticle(w http.ResponseWriter, r *http.Request) (models.NewsArticle, bool) {
+	id := mux.Vars(r)["id"]
+	article, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		log.Printf("Article with ID %s not found: %v", id, err)
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return article, false
+	}
+	return article, true
+}
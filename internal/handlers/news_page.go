@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"school-website/internal/config"
+	"school-website/internal/database"
+	"school-website/internal/markup"
+	"school-website/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// NewsPageHandler serves the public article pages under /news/{slug}
+type NewsPageHandler struct {
+	db     *database.Database
+	config *config.Config
+}
+
+func NewNewsPageHandler(db *database.Database, cfg *config.Config) *NewsPageHandler {
+	return &NewsPageHandler{db: db, config: cfg}
+}
+
+// newsPageData is passed to templates/news_article.html
+type newsPageData struct {
+	Article      models.NewsArticle
+	Content      template.HTML // sanitized when the article was saved
+	SiteName     string
+	CanonicalURL string
+	Description  string
+	ImageURL     string
+	Published    time.Time
+}
+
+var russianMonths = [...]string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+var newsPageFuncs = template.FuncMap{
+	// formatDate renders a date the way the site shows it, e.g. "5 мая 2024"
+	"formatDate": func(t time.Time) string {
+		t = t.Local()
+		return strconv.Itoa(t.Day()) + " " + russianMonths[t.Month()-1] + " " + strconv.Itoa(t.Year())
+	},
+	// srcset lists the resized variants of one format for a srcset attribute
+	"srcset":       models.Srcset,
+	"fallbackType": models.FallbackType,
+}
+
+// ArticlePage renders the article page for /news/{slug} on the server so
+// that crawlers and link previews see the title, description and image.
+// Numeric keys are old-style IDs and are permanently redirected to the slug URL.
+func (h *NewsPageHandler) ArticlePage(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["slug"]
+
+	if _, err := strconv.Atoi(key); err == nil {
+		article, err := h.db.GetNewsArticle(key)
+		if err != nil || !article.IsPublic(time.Now()) || article.URL == "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, article.URL, http.StatusMovedPermanently)
+		return
+	}
+
+	article, err := h.db.GetNewsArticleBySlug(key)
+	if err != nil || !article.IsPublic(time.Now()) {
+		log.Printf("News page %s not found: %v", key, err)
+		http.NotFound(w, r)
+		return
+	}
+
+	base := siteBaseURL(h.config, w, r)
+	data := newsPageData{
+		Article:      article,
+		Content:      template.HTML(article.ContentHTML),
+		SiteName:     h.config.SiteName,
+		CanonicalURL: base + article.URL,
+		Description:  markup.Excerpt(markup.PlainText(article.ContentHTML), 200),
+		Published:    articleDate(article),
+	}
+	if article.ImageURL != "" {
+		data.ImageURL = absoluteURL(base, article.ImageURL)
+	} else if len(article.Images) > 0 {
+		// Articles with only a gallery use its first photo for link previews
+		data.ImageURL = absoluteURL(base, article.Images[0].URL)
+	}
+
+	filePath := filepath.Join(h.config.TemplatesDir, "news_article.html")
+	tmpl, err := template.New("news_article.html").Funcs(newsPageFuncs).ParseFiles(filePath)
+	if err != nil {
+		log.Printf("Error parsing template %s: %v", filePath, err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		log.Printf("Error rendering template %s: %v", filePath, err)
+	}
+}
+
+// LegacyArticlePage handles the old /news_article.html?id=42 links by
+// redirecting them to the article's permalink.
+func (h *NewsPageHandler) LegacyArticlePage(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		article, err := h.db.GetNewsArticle(id)
+		if err == nil && article.IsPublic(time.Now()) && article.URL != "" {
+			http.Redirect(w, r, article.URL, http.StatusMovedPermanently)
+			return
+		}
+	}
+
+	// Unknown IDs still get the page, which falls back to the demo articles
+	http.ServeFile(w, r, filepath.Join(h.config.PublicDir, "news_article.html"))
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"school-website/internal/config"
+	"school-website/internal/database"
+	"school-website/internal/models"
+	"school-website/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func newNewsPageRouter(t *testing.T) (*mux.Router, *database.Database) {
+	t.Helper()
+	dir := t.TempDir()
+	uploadDir := filepath.Join(dir, "uploads")
+	db, err := database.New(filepath.Join(dir, "school.db"), storage.NewLocal(uploadDir), uploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		TemplatesDir: "../../templates",
+		PublicDir:    "../../public",
+		SiteURL:      "https://school.example",
+		SiteName:     "Школа №1",
+	}
+	h := NewNewsPageHandler(db, cfg)
+	r := mux.NewRouter()
+	r.HandleFunc("/news/{slug}", h.ArticlePage).Methods("GET")
+	r.HandleFunc("/news_article.html", h.LegacyArticlePage)
+	return r, db
+}
+
+func saveTestNews(t *testing.T, db *database.Database, article models.NewsArticle) string {
+	t.Helper()
+	id, err := db.SaveNews(article)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func TestArticlePageRendersMetadata(t *testing.T) {
+	r, db := newNewsPageRouter(t)
+	saveTestNews(t, db, models.NewsArticle{
+		Title:       "Олимпиада <по физике>",
+		ContentHTML: "<p>Ученики 9 класса</p><p>заняли первое место.</p>",
+		ImageURL:    "/uploads/cover.jpg",
+		Status:      models.NewsPublished,
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/news/olimpiada-po-fizike", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	page := w.Body.String()
+	for _, want := range []string{
+		`<title>Олимпиада &lt;по физике&gt; - Школа №1</title>`,
+		`<link rel="canonical" href="https://school.example/news/olimpiada-po-fizike">`,
+		`<meta property="og:description" content="Ученики 9 класса заняли первое место.">`,
+		`<meta property="og:image" content="https://school.example/uploads/cover.jpg">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+}
+
+func TestArticlePageRedirects(t *testing.T) {
+	r, db := newNewsPageRouter(t)
+	published := saveTestNews(t, db, models.NewsArticle{Title: "Последний звонок", Status: models.NewsPublished})
+	draft := saveTestNews(t, db, models.NewsArticle{Title: "Черновик", Status: models.NewsDraft})
+	future := time.Now().Add(24 * time.Hour)
+	scheduled := saveTestNews(t, db, models.NewsArticle{Title: "Завтра", Status: models.NewsScheduled, PublishAt: &future})
+
+	tests := []struct {
+		name     string
+		url      string
+		code     int
+		location string
+	}{
+		{"id redirects to the slug", "/news/" + published, http.StatusMovedPermanently, "/news/posledniy-zvonok"},
+		{"old page link redirects", "/news_article.html?id=" + published, http.StatusMovedPermanently, "/news/posledniy-zvonok"},
+		{"slug is served", "/news/posledniy-zvonok", http.StatusOK, ""},
+		{"draft id is hidden", "/news/" + draft, http.StatusNotFound, ""},
+		{"draft slug is hidden", "/news/chernovik", http.StatusNotFound, ""},
+		{"scheduled slug is hidden", "/news/zavtra", http.StatusNotFound, ""},
+		{"scheduled id is hidden", "/news/" + scheduled, http.StatusNotFound, ""},
+		{"unknown slug", "/news/net-takoy", http.StatusNotFound, ""},
+		{"old page link to a draft falls back to the page", "/news_article.html?id=" + draft, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			if w.Code != tt.code {
+				t.Fatalf("GET %s: status = %d, want %d", tt.url, w.Code, tt.code)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("GET %s: Location = %q, want %q", tt.url, got, tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"school-website/internal/diff"
+	"school-website/internal/markup"
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// fieldChange describes a changed single-line field of an article
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// recordRevision stores the current state of the article as a new revision.
+// Failures are only logged so that the change itself is not reported as failed.
+func (h *NewsHandler) recordRevision(r *http.Request, article models.NewsArticle, action string, restoredFrom int) int {
+	var userID int
+	var username string
+	if user := middleware.CurrentUser(r); user != nil {
+		userID, username = user.ID, user.Username
+	}
+
+	number, err := h.db.SaveNewsRevision(article, action, restoredFrom, userID, username)
+	if err != nil {
+		log.Printf("Warning: failed to save revision of news %d: %v", article.ID, err)
+	}
+	return number
+}
+
+// GetRevisions lists the revisions of an article, newest first
+func (h *NewsHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	if _, err := h.db.GetNewsArticle(id); err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.db.GetNewsRevisions(id)
+	if err != nil {
+		log.Printf("Error getting revisions of news %s: %v", id, err)
+		http.Error(w, "Failed to get revisions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetRevision returns a single revision with its full content
+func (h *NewsHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rev, ok := h.loadRevision(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(rev)
+}
+
+// DiffRevision compares a revision with the one given in ?against= (by
+// default the previous revision) and returns the changed fields and a line
+// diff of the content.
+func (h *NewsHandler) DiffRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rev, ok := h.loadRevision(w, r)
+	if !ok {
+		return
+	}
+
+	var base models.NewsRevision
+	againstNumber := rev.Number - 1
+	if value := r.URL.Query().Get("against"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid against revision", http.StatusBadRequest)
+			return
+		}
+		againstNumber = n
+	}
+	if againstNumber > 0 {
+		var err error
+		base, err = h.db.GetNewsRevision(mux.Vars(r)["id"], againstNumber)
+		if err != nil {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	fields := map[string]fieldChange{}
+	if base.Title != rev.Title {
+		fields["title"] = fieldChange{base.Title, rev.Title}
+	}
+	if base.Slug != rev.Slug {
+		fields["slug"] = fieldChange{base.Slug, rev.Slug}
+	}
+	if base.ImageURL != rev.ImageURL {
+		fields["image_url"] = fieldChange{base.ImageURL, rev.ImageURL}
+	}
+	if base.Status != rev.Status {
+		fields["status"] = fieldChange{base.Status, rev.Status}
+	}
+	if !sameTime(base.PublishAt, rev.PublishAt) {
+		fields["publish_at"] = fieldChange{base.PublishAt, rev.PublishAt}
+	}
+
+	content := diff.Lines(base.Content, rev.Content)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":            againstNumber,
+		"to":              rev.Number,
+		"fields":          fields,
+		"content":         content,
+		"content_changed": diff.Changed(content),
+	})
+}
+
+// RestoreRevision copies the title, content and image of an old revision
+// back into the article and stores the result as a new revision. The slug,
+// status and publish time stay as they are so links and visibility don't change.
+func (h *NewsHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rev, ok := h.loadRevision(w, r)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	existingArticle, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	contentHTML, err := markup.Render(rev.Content)
+	if err != nil {
+		log.Printf("Error rendering content of revision %d: %v", rev.Number, err)
+		http.Error(w, "Failed to restore revision", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.db.UpdateNewsArticle(id, models.NewsArticle{
+		Title:         rev.Title,
+		Content:       rev.Content,
+		ContentHTML:   contentHTML,
+		ImageURL:      rev.ImageURL,
+		ImageVariants: rev.ImageVariants,
+		Status:        existingArticle.Status,
+		PublishAt:     existingArticle.PublishAt,
+	})
+	if err != nil {
+		log.Printf("Error restoring revision %d of news %s: %v", rev.Number, id, err)
+		http.Error(w, "Failed to restore revision", http.StatusInternalServerError)
+		return
+	}
+
+	restored, err := h.db.GetNewsArticle(id)
+	if err != nil {
+		http.Error(w, "Article not found", http.StatusNotFound)
+		return
+	}
+
+	number := h.recordRevision(r, restored, models.AuditRestore, rev.Number)
+	h.audit.Record(r, models.AuditRestore, models.EntityNews, id, existingArticle, restored)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"message":  "Revision restored",
+		"revision": number,
+		"article":  restored,
+	})
+}
+
+// loadRevision reads the {id} and {rev} route variables and loads the revision
+func (h *NewsHandler) loadRevision(w http.ResponseWriter, r *http.Request) (models.NewsRevision, bool) {
+	vars := mux.Vars(r)
+
+	number, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		http.Error(w, "Invalid revision number", http.StatusBadRequest)
+		return models.NewsRevision{}, false
+	}
+
+	rev, err := h.db.GetNewsRevision(vars["id"], number)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error getting revision: %v", err)
+			http.Error(w, "Failed to get revision", http.StatusInternalServerError)
+		}
+		return models.NewsRevision{}, false
+	}
+
+	return rev, true
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// parsePagination reads the limit and offset query parameters. A missing
+// limit falls back to defaultLimit and values above maxLimit are capped.
+func parsePagination(query url.Values, defaultLimit, maxLimit int) (int, int, error) {
+	limit, offset := defaultLimit, 0
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = n
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// parseDateParam parses a YYYY-MM-DD or RFC 3339 query value. For plain
+// dates used as an upper bound the start of the next day is returned so
+// that the whole day is included.
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
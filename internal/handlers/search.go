@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"school-website/internal/database"
+	"school-website/internal/models"
+	"school-website/internal/search"
+)
+
+// SearchHandler serves the site-wide search over news, documents and folders
+type SearchHandler struct {
+	db *database.Database
+}
+
+func NewSearchHandler(db *database.Database) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// Search finds published news, documents and folders by the words in "q".
+// "type" limits the results to a comma-separated list of types (news,
+// document, folder); the counts of all types are returned anyway.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		http.Error(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(q)) > search.MaxQueryLength {
+		http.Error(w, "Search query is too long", http.StatusBadRequest)
+		return
+	}
+
+	filter := models.SearchFilter{Terms: search.Terms(q)}
+	if types := query.Get("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if _, ok := models.SearchTypeLabels[t]; !ok {
+				http.Error(w, fmt.Sprintf("invalid type %q", t), http.StatusBadRequest)
+				return
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	var err error
+	if filter.Limit, filter.Offset, err = parsePagination(query, defaultSearchLimit, maxSearchLimit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, total, counts, err := h.db.SearchSite(filter)
+	if err != nil {
+		log.Printf("Error searching the site for %q: %v", q, err)
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":  q,
+		"items":  results,
+		"total":  total,
+		"counts": counts,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"school-website/internal/config"
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+	"school-website/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultShareHours = 72
+	maxShareHours     = 30 * 24
+	maxShareDownloads = 1000
+	maxShareNote      = 255
+)
+
+// ShareHandler manages share links of documents and serves the downloads
+// through them
+type ShareHandler struct {
+	shares    *services.ShareService
+	documents *services.DocumentService
+	audit     *services.AuditService
+	cfg       *config.Config
+}
+
+func NewShareHandler(shares *services.ShareService, documents *services.DocumentService, audit *services.AuditService, cfg *config.Config) *ShareHandler {
+	return &ShareHandler{shares: shares, documents: documents, audit: audit, cfg: cfg}
+}
+
+// withAbsoluteURLs turns the link addresses into full URLs that can be sent
+// by e-mail or messenger
+func (h *ShareHandler) withAbsoluteURLs(w http.ResponseWriter, r *http.Request, shares []models.DocumentShare) []models.DocumentShare {
+	base := siteBaseURL(h.cfg, w, r)
+	for i := range shares {
+		shares[i].URL = absoluteURL(base, shares[i].URL)
+	}
+	return shares
+}
+
+// CreateShare makes a share link of a document. Expects {"hours": 72,
+// "max_downloads": 0, "note": "..."}; all fields are optional, zero
+// max_downloads means no limit.
+func (h *ShareHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	var input struct {
+		Hours        *int   `json:"hours"`
+		MaxDownloads int    `json:"max_downloads"`
+		Note         string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hours := defaultShareHours
+	if input.Hours != nil {
+		hours = *input.Hours
+	}
+	if hours < 1 || hours > maxShareHours {
+		http.Error(w, "hours must be between 1 and 720", http.StatusBadRequest)
+		return
+	}
+	if input.MaxDownloads < 0 || input.MaxDownloads > maxShareDownloads {
+		http.Error(w, "max_downloads must be between 0 and 1000", http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(input.Note)
+	if len([]rune(note)) > maxShareNote {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+
+	doc, err := h.documents.GetDocument(id)
+	if err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	share, err := h.shares.CreateShare(doc, time.Duration(hours)*time.Hour, input.MaxDownloads, note, middleware.CurrentUser(r))
+	if err != nil {
+		log.Printf("Error creating share link of document %s: %v", id, err)
+		http.Error(w, "Failed to create share link", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r, models.AuditCreate, models.EntityShare, share.ID, nil, share)
+
+	created := h.withAbsoluteURLs(w, r, []models.DocumentShare{*share})[0]
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetDocumentShares lists the links of one document that still work, or
+// all of them with all=true
+func (h *ShareHandler) GetDocumentShares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	if _, err := h.documents.GetDocument(id); err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeShares(w, r, id)
+}
+
+// GetShares lists the links of all documents that still work, or all of
+// them with all=true
+func (h *ShareHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	h.writeShares(w, r, "")
+}
+
+func (h *ShareHandler) writeShares(w http.ResponseWriter, r *http.Request, documentID string) {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+
+	shares, err := h.shares.GetShares(documentID, !all)
+	if err != nil {
+		log.Printf("Error getting share links: %v", err)
+		http.Error(w, "Failed to get share links", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(h.withAbsoluteURLs(w, r, shares))
+}
+
+// RevokeShare disables a link; the record stays for the history
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.shares.GetShare(id)
+	if err != nil {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.shares.RevokeShare(id); err != nil {
+		log.Printf("Error revoking share link %s: %v", id, err)
+		http.Error(w, "Failed to revoke share link", http.StatusInternalServerError)
+		return
+	}
+
+	revoked, err := h.shares.GetShare(id)
+	if err != nil {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	h.audit.Record(r, models.AuditRevoke, models.EntityShare, id, existing, revoked)
+
+	json.NewEncoder(w).Encode(h.withAbsoluteURLs(w, r, []models.DocumentShare{*revoked})[0])
+}
+
+// Download serves the document of a share link (/share/{id}/{signature}) to
+// anyone holding the link, whatever the visibility of the document. Every
+// GET request counts as a download; HEAD requests don't.
+func (h *ShareHandler) Download(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	// The links are personal: keep them out of caches, search engines and
+	// the Referer of other sites
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	share, err := h.shares.OpenShare(vars["id"], vars["signature"])
+	if err == nil {
+		var doc *models.Document
+		if doc, err = h.documents.GetDocument(strconv.Itoa(share.DocumentID)); err == nil {
+			h.serveShared(w, r, share, doc)
+			return
+		}
+		err = services.ErrShareNotFound
+	}
+
+	switch {
+	case errors.Is(err, services.ErrShareNotFound):
+		http.Error(w, "Link not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrShareInactive):
+		http.Error(w, "This link has expired or is no longer valid", http.StatusGone)
+	default:
+		log.Printf("Error opening share link %s: %v", vars["id"], err)
+		http.Error(w, "Failed to open link", http.StatusInternalServerError)
+	}
+}
+
+func (h *ShareHandler) serveShared(w http.ResponseWriter, r *http.Request, share *models.DocumentShare, doc *models.Document) {
+	file, err := h.documents.OpenDocument(doc)
+	if err != nil {
+		log.Printf("Error opening file of document %d: %v", doc.ID, err)
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "File not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to read document", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	// Every GET is counted, so each one must send the whole file: ranges and
+	// 304 answers would let a PDF viewer or a download manager use up a
+	// limited link with partial requests
+	if r.Method == http.MethodGet {
+		if err := h.shares.CountDownload(share); err != nil {
+			if errors.Is(err, services.ErrShareInactive) {
+				http.Error(w, "This link has expired or is no longer valid", http.StatusGone)
+			} else {
+				log.Printf("Error counting download of share link %d: %v", share.ID, err)
+				http.Error(w, "Failed to open link", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", doc.OriginalName))
+	w.Header().Set("Content-Type", doc.FileType)
+	w.Header().Set("Accept-Ranges", "none")
+	streamObject(w, r, doc.FileName, file)
+	log.Printf("Document downloaded via share link %d: %s (ID: %d)", share.ID, doc.OriginalName, doc.ID)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StaticHandler serves the public directory. The upload directory usually
+// lives inside it, but uploaded files must only be reachable through
+// UploadsHandler, which keeps document files (and their old versions) away
+// from the public, so that part of the tree is answered with 404.
+type StaticHandler struct {
+	files  http.Handler
+	hidden string
+}
+
+func NewStaticHandler(publicDir, uploadDir string) *StaticHandler {
+	h := &StaticHandler{files: http.FileServer(http.Dir(publicDir))}
+
+	rel, err := filepath.Rel(publicDir, uploadDir)
+	if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		h.hidden = "/" + filepath.ToSlash(rel)
+	}
+	return h
+}
+
+func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.hidden != "" {
+		// http.FileServer cleans the path the same way before opening the file
+		name := path.Clean("/" + r.URL.Path)
+		if name == h.hidden || strings.HasPrefix(name, h.hidden+"/") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	h.files.ServeHTTP(w, r)
+}
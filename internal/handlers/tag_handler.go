@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"school-website/internal/database"
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+	"school-website/internal/services"
+	"school-website/internal/slug"
+
+	"github.com/gorilla/mux"
+)
+
+const maxTagNameLength = 50
+
+type TagHandler struct {
+	db    *database.Database
+	audit *services.AuditService
+}
+
+func NewTagHandler(db *database.Database, audit *services.AuditService) *TagHandler {
+	return &TagHandler{db: db, audit: audit}
+}
+
+// GetTags lists tags with article counts. Visitors only get tags that have
+// published articles; the admin panel gets every tag.
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	tags, err := h.db.GetTags(middleware.CurrentUser(r) == nil)
+	if err != nil {
+		log.Printf("Error getting tags: %v", err)
+		http.Error(w, "Failed to get tags", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.TagInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, tagSlug, status, err := h.validateTag(input, 0)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	id, err := h.db.CreateTag(name, tagSlug)
+	if err != nil {
+		log.Printf("Error creating tag: %v", err)
+		http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+		return
+	}
+
+	tag := models.Tag{ID: int(id), Name: name, Slug: tagSlug}
+	h.audit.Record(r, models.AuditCreate, models.EntityTag, id, nil, tag)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// UpdateTag renames a tag. The slug is kept unless a new one is given.
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	existing, err := h.db.GetTag(id)
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	var input models.TagInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(input.Slug) == "" {
+		input.Slug = existing.Slug
+	}
+
+	name, tagSlug, status, err := h.validateTag(input, existing.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.db.UpdateTag(id, name, tagSlug); err != nil {
+		log.Printf("Error updating tag: %v", err)
+		http.Error(w, "Failed to update tag", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := h.db.GetTag(id)
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	h.audit.Record(r, models.AuditUpdate, models.EntityTag, id, existing, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteTag removes the tag from all articles and deletes it
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	existing, err := h.db.GetTag(id)
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.DeleteTag(id); err != nil {
+		log.Printf("Error deleting tag: %v", err)
+		http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		return
+	}
+	h.audit.Record(r, models.AuditDelete, models.EntityTag, id, existing, nil)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Tag successfully deleted",
+		"id":      id,
+	})
+}
+
+// validateTag normalizes the name and slug of a tag and checks that they are
+// not used by another tag. It returns the HTTP status to report on failure.
+func (h *TagHandler) validateTag(input models.TagInput, excludeID int) (string, string, int, error) {
+	name := strings.Join(strings.Fields(input.Name), " ")
+	if name == "" {
+		return "", "", http.StatusBadRequest, fmt.Errorf("tag name is required")
+	}
+	if len([]rune(name)) > maxTagNameLength {
+		return "", "", http.StatusBadRequest, fmt.Errorf("tag name is too long")
+	}
+
+	taken, err := h.db.TagNameTaken(name, excludeID)
+	if err != nil {
+		log.Printf("Error checking tag name: %v", err)
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to save tag")
+	}
+	if taken {
+		return "", "", http.StatusConflict, fmt.Errorf("tag already exists")
+	}
+
+	if value := strings.TrimSpace(input.Slug); value != "" {
+		tagSlug := slug.Make(value)
+		if tagSlug == "" {
+			return "", "", http.StatusBadRequest, fmt.Errorf("invalid slug")
+		}
+		taken, err := h.db.TagSlugTaken(tagSlug, excludeID)
+		if err != nil {
+			log.Printf("Error checking tag slug: %v", err)
+			return "", "", http.StatusInternalServerError, fmt.Errorf("failed to save tag")
+		}
+		if taken {
+			return "", "", http.StatusConflict, fmt.Errorf("slug is already used by another tag")
+		}
+		return name, tagSlug, 0, nil
+	}
+
+	tagSlug, err := h.db.UniqueTagSlug(name, excludeID)
+	if err != nil {
+		log.Printf("Error generating tag slug: %v", err)
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to save tag")
+	}
+	return name, tagSlug, 0, nil
+}
+
+// parseTagNames splits a comma-separated list of tag names, dropping empty
+// entries and duplicates.
+func parseTagNames(value string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		name := strings.Join(strings.Fields(part), " ")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		if len([]rune(name)) > maxTagNameLength {
+			return nil, fmt.Errorf("tag name %q is too long", name)
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"school-website/internal/filetype"
+	"school-website/internal/services"
+	"school-website/internal/slug"
+	"school-website/internal/storage"
+)
+
+// UploadsHandler serves uploaded files (/uploads/...) from the file storage,
+// whichever backend it is. Document files, including old versions, are not
+// served here: they are downloaded through the documents API, which checks
+// their visibility and keeps versions admin-only.
+type UploadsHandler struct {
+	files storage.Storage
+}
+
+func NewUploadsHandler(files storage.Storage) *UploadsHandler {
+	return &UploadsHandler{files: files}
+}
+
+func (h *UploadsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, ok := storage.KeyFromURL(r.URL.Path)
+	if !ok || strings.HasPrefix(key, services.DocumentsPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := h.files.Open(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("Error opening upload %s: %v", key, err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// Stored names are random and never reused, so files can be cached for long
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	serveObject(w, r, path.Base(key), file)
+}
+
+// serveObject writes a stored file. Local files go through http.ServeContent
+// (ranges, If-Modified-Since); files from S3 are streamed as they arrive.
+func serveObject(w http.ResponseWriter, r *http.Request, name string, file *storage.Object) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if w.Header().Get("Content-Type") == "" && file.ContentType != "" {
+		w.Header().Set("Content-Type", file.ContentType)
+	}
+
+	if content, ok := file.ReadCloser.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, file.ModTime, content)
+		return
+	}
+	streamObject(w, r, name, file)
+}
+
+// streamObject writes a whole stored file with a 200 response, ignoring
+// Range and conditional request headers
+func streamObject(w http.ResponseWriter, r *http.Request, name string, file *storage.Object) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if w.Header().Get("Content-Type") == "" && file.ContentType != "" {
+		w.Header().Set("Content-Type", file.ContentType)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if !file.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", file.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if file.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Error sending file %s: %v", name, err)
+	}
+}
+
+// writeRejectedUpload answers with 415 and a JSON description if err is a
+// file of a type that is not allowed. It reports false for any other error,
+// which the caller handles as before.
+func writeRejectedUpload(w http.ResponseWriter, err error) bool {
+	var rejected *filetype.NotAllowedError
+	if !errors.As(err, &rejected) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":         "Unsupported file type",
+		"message":       fmt.Sprintf("File %q is %s, which is not allowed here", rejected.Name, rejected.Type),
+		"file":          rejected.Name,
+		"detected_type": rejected.Type,
+		"allowed_types": rejected.Allowed,
+	})
+	return true
+}
+
+// allRejected reports whether every upload error is a file of a type that is not allowed
+func allRejected(errs []error) bool {
+	for _, err := range errs {
+		var rejected *filetype.NotAllowedError
+		if !errors.As(err, &rejected) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentDisposition builds a Content-Disposition header for a file name
+// that may contain Cyrillic: an ASCII transliteration in filename for old
+// clients and the exact UTF-8 name in filename* (RFC 5987).
+func contentDisposition(disposition, name string) string {
+	ext := filepath.Ext(name)
+	fallback := slug.Make(strings.TrimSuffix(name, ext))
+	if fallback == "" {
+		fallback = "file"
+	}
+	fallback += asciiOnly(ext)
+
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback, encodeRFC5987(name))
+}
+
+// encodeRFC5987 percent-encodes everything except the attr-char set of RFC 5987
+func encodeRFC5987(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if isAttrChar(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isAttrChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// asciiOnly drops the characters of an extension such as ".pdf" that can't
+// appear in a quoted ASCII file name
+func asciiOnly(ext string) string {
+	var b strings.Builder
+	for _, c := range []byte(ext) {
+		if isAttrChar(c) {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
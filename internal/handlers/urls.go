@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"school-website/internal/config"
+)
+
+// siteBaseURL returns the configured public site URL or, when SITE_URL is
+// not set, derives it from the request (honouring X-Forwarded-Proto). In the
+// latter case the response depends on headers the client controls, so it is
+// marked with Vary to keep a forged Host out of shared caches.
+func siteBaseURL(cfg *config.Config, w http.ResponseWriter, r *http.Request) string {
+	if cfg.SiteURL != "" {
+		return cfg.SiteURL
+	}
+	w.Header().Add("Vary", "Host, X-Forwarded-Proto")
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + r.Host
+}
+
+// absoluteURL resolves a site-relative URL such as /uploads/x.jpg against base
+func absoluteURL(base, url string) string {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return url
+	}
+	if !strings.HasPrefix(url, "/") {
+		url = "/" + url
+	}
+	return base + url
+}
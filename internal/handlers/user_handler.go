@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+	"school-website/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type UserHandler struct {
+	service *services.UserService
+	audit   *services.AuditService
+}
+
+func NewUserHandler(service *services.UserService, audit *services.AuditService) *UserHandler {
+	return &UserHandler{service: service, audit: audit}
+}
+
+func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	users, err := h.service.GetUsers()
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		http.Error(w, "Failed to get users", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var input models.UserInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.CreateUser(input)
+	if err != nil {
+		log.Printf("Error creating user %s: %v", input.Username, err)
+		switch {
+		case errors.Is(err, services.ErrWeakPassword) || errors.Is(err, services.ErrInvalidRole) ||
+			strings.Contains(err.Error(), "required"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "UNIQUE"):
+			http.Error(w, "User with this username already exists", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.audit.Record(r, models.AuditCreate, models.EntityUser, user.ID, nil, user)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+func (h *UserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+func (h *UserHandler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if current := middleware.CurrentUser(r); !active && current != nil && current.ID == id {
+		http.Error(w, "You cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetUser(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.service.SetActive(id, active); err != nil {
+		log.Printf("Error updating user %d: %v", id, err)
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	action := models.AuditDisable
+	if active {
+		action = models.AuditEnable
+	}
+	h.audit.Record(r, action, models.EntityUser, id, before, user)
+
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *UserHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input models.RoleChange
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if current := middleware.CurrentUser(r); current != nil && current.ID == id && input.Role != models.RoleAdministrator {
+		http.Error(w, "You cannot remove the administrator role from your own account", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.service.GetUser(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.service.SetRole(id, input.Role); err != nil {
+		log.Printf("Error changing role for user %d: %v", id, err)
+		switch {
+		case errors.Is(err, services.ErrInvalidRole):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to change role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	h.audit.Record(r, models.AuditChangeRole, models.EntityUser, id, before, user)
+	json.NewEncoder(w).Encode(user)
+}
+
+// GetCurrentUser returns the logged in user together with the sections of
+// the admin panel they may manage, so the admin pages can hide buttons.
+func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := middleware.CurrentUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":        user,
+		"permissions": user.Permissions(),
+	})
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// An empty body means "generate a random password"
+	var input models.PasswordReset
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	password, err := h.service.ResetPassword(id, input.Password)
+	if err != nil {
+		log.Printf("Error resetting password for user %d: %v", id, err)
+		switch {
+		case errors.Is(err, services.ErrWeakPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.audit.Record(r, models.AuditResetPassword, models.EntityUser, id, nil, nil)
+
+	response := map[string]interface{}{
+		"message": "Password reset successfully",
+		"id":      id,
+	}
+	if input.Password == "" {
+		response["password"] = password
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,216 @@
+// Package imaging prepares uploaded photos for the web: it applies the EXIF
+// orientation, drops all metadata (including GPS coordinates) by re-encoding
+// and produces resized variants in the original format and in WebP.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// Size is a named target width of a variant
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes are the variants produced for every photo. Images are never
+// upscaled, so small photos may get fewer variants.
+var Sizes = []Size{
+	{Name: "thumb", Width: 320},
+	{Name: "card", Width: 640},
+	{Name: "full", Width: 1600},
+}
+
+const (
+	jpegQuality = 82
+	webpQuality = 80
+
+	// maxPixels protects the server from decompression bombs
+	maxPixels = 50_000_000
+)
+
+// ErrAnimated is returned for animated GIFs, which are kept as they are
+var ErrAnimated = errors.New("animated image")
+
+// Variant is one encoded version of a photo
+type Variant struct {
+	Size   string // name of the Size, e.g. "card"
+	Width  int
+	Height int
+	Type   string // MIME type
+	Ext    string // file extension including the dot
+	Data   []byte
+}
+
+// Process decodes an uploaded image and returns its variants, largest last
+// for each format. Photos with transparency are re-encoded as PNG, all
+// others as JPEG; every size is also encoded as WebP.
+func Process(data []byte) ([]Variant, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %v", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("image is too large: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if format == "gif" {
+		if g, err := gif.DecodeAll(bytes.NewReader(data)); err == nil && len(g.Image) > 1 {
+			return nil, ErrAnimated
+		}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	if format == "jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	opaque := isOpaque(src)
+
+	// Plan the widths from small to large; small originals are not upscaled,
+	// so sizes that would come out the same width are skipped.
+	type step struct {
+		name  string
+		width int
+	}
+	var plan []step
+	for _, size := range Sizes {
+		width := size.Width
+		if srcWidth := src.Bounds().Dx(); srcWidth < width {
+			width = srcWidth
+		}
+		if len(plan) > 0 && plan[len(plan)-1].width == width {
+			continue
+		}
+		plan = append(plan, step{size.Name, width})
+	}
+
+	// Resize from the largest size down, each step from the previous result
+	var variants []Variant
+	current := src
+	for i := len(plan) - 1; i >= 0; i-- {
+		if current.Bounds().Dx() > plan[i].width {
+			current = resize(current, plan[i].width)
+		}
+		encoded, err := encodeVariants(current, plan[i].name, opaque)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(encoded, variants...)
+	}
+
+	return variants, nil
+}
+
+func encodeVariants(img image.Image, sizeName string, opaque bool) ([]Variant, error) {
+	bounds := img.Bounds()
+	base := Variant{Size: sizeName, Width: bounds.Dx(), Height: bounds.Dy()}
+
+	var buf bytes.Buffer
+	fallback := base
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %v", err)
+		}
+		fallback.Type, fallback.Ext = "image/jpeg", ".jpg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode png: %v", err)
+		}
+		fallback.Type, fallback.Ext = "image/png", ".png"
+	}
+	fallback.Data = buf.Bytes()
+
+	webpData, err := webp.EncodeRGBA(img, webpQuality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webp: %v", err)
+	}
+	webpVariant := base
+	webpVariant.Type, webpVariant.Ext, webpVariant.Data = "image/webp", ".webp", webpData
+
+	return []Variant{fallback, webpVariant}, nil
+}
+
+// resize scales img down to the given width, keeping the aspect ratio
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xFFFF {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// applyOrientation rotates and flips img according to an EXIF orientation
+// value so that it is displayed upright without the tag.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,234 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// markedImage is a blue w×h image with a red top-left quarter, so that
+// rotations and flips can be told apart after lossy encoding
+func markedImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 && y < h/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// exifSegment builds an APP1 segment whose first IFD holds the orientation
+// tag and a GPS IFD pointer, the way cameras write them
+func exifSegment(order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 8+2+2*12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 2)
+
+	entry := tiff[10:]
+	order.PutUint16(entry, 0x0112) // orientation, SHORT
+	order.PutUint16(entry[2:], 3)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], uint16(orientation))
+
+	entry = tiff[22:]
+	order.PutUint16(entry, 0x8825) // GPS IFD pointer, LONG
+	order.PutUint16(entry[2:], 4)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint32(entry[8:], 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithOrientation encodes img as JPEG with an EXIF orientation tag
+func jpegWithOrientation(t *testing.T, img image.Image, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// The EXIF segment goes right after the start-of-image marker
+	return append(append(append([]byte{}, data[:2]...), exifSegment(order, orientation)...), data[2:]...)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func TestJpegOrientation(t *testing.T) {
+	img := markedImage(8, 8)
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for orientation := 1; orientation <= 8; orientation++ {
+			data := jpegWithOrientation(t, img, order, orientation)
+			if got := jpegOrientation(data); got != orientation {
+				t.Errorf("%v orientation %d read as %d", order, orientation, got)
+			}
+		}
+	}
+
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, img, nil)
+	if got := jpegOrientation(plain.Bytes()); got != 1 {
+		t.Errorf("JPEG without EXIF: orientation %d, want 1", got)
+	}
+	if got := jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF}); got != 1 {
+		t.Errorf("truncated JPEG: orientation %d, want 1", got)
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	// A 60×40 photo with the red quarter top-left, and where that quarter
+	// ends up once the image is displayed upright
+	tests := []struct {
+		orientation   int
+		width, height int
+		redX, redY    int
+	}{
+		{1, 60, 40, 15, 10},
+		{2, 60, 40, 45, 10},
+		{3, 60, 40, 45, 30},
+		{4, 60, 40, 15, 30},
+		{5, 40, 60, 10, 15},
+		{6, 40, 60, 30, 15},
+		{7, 40, 60, 30, 45},
+		{8, 40, 60, 10, 45},
+	}
+
+	for _, tt := range tests {
+		data := jpegWithOrientation(t, markedImage(60, 40), binary.BigEndian, tt.orientation)
+		variants, err := Process(data)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		v := variants[0]
+		if v.Width != tt.width || v.Height != tt.height {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, v.Width, v.Height, tt.width, tt.height)
+			continue
+		}
+
+		out, err := jpeg.Decode(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isRed(out.At(tt.redX, tt.redY)) {
+			t.Errorf("orientation %d: red quarter is not at (%d, %d)", tt.orientation, tt.redX, tt.redY)
+		}
+		if bytes.Contains(v.Data, []byte("Exif")) {
+			t.Errorf("orientation %d: EXIF is kept in the output", tt.orientation)
+		}
+	}
+}
+
+func TestProcessSizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		want   []Size // expected variants, smallest first
+	}{
+		{"large photo", 2000, 1000, []Size{{"thumb", 320}, {"card", 640}, {"full", 1600}}},
+		{"medium photo is not upscaled", 500, 250, []Size{{"thumb", 320}, {"card", 500}}},
+		{"small photo keeps one size", 200, 100, []Size{{"thumb", 200}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := Process(encodePNG(t, markedImage(tt.width, tt.height)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(variants) != 2*len(tt.want) {
+				t.Fatalf("got %d variants, want %d", len(variants), 2*len(tt.want))
+			}
+			for i, size := range tt.want {
+				for j, typ := range []string{"image/jpeg", "image/webp"} {
+					v := variants[2*i+j]
+					if v.Size != size.Name || v.Width != size.Width || v.Height != size.Width*tt.height/tt.width || v.Type != typ {
+						t.Errorf("variant %d = %s %dx%d %s, want %s %dx%d %s", 2*i+j, v.Size, v.Width, v.Height, v.Type,
+							size.Name, size.Width, size.Width*tt.height/tt.width, typ)
+					}
+					cfg, _, err := image.DecodeConfig(bytes.NewReader(v.Data))
+					if err != nil || cfg.Width != v.Width || cfg.Height != v.Height {
+						t.Errorf("variant %d decodes as %dx%d, %v", 2*i+j, cfg.Width, cfg.Height, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProcessKeepsTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 50, 50))
+	img.Set(10, 10, red)
+
+	variants, err := Process(encodePNG(t, img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants[0].Type != "image/png" || variants[0].Ext != ".png" {
+		t.Errorf("transparent image encoded as %s", variants[0].Type)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{red, blue})
+	var animated bytes.Buffer
+	gif.EncodeAll(&animated, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}})
+	if _, err := Process(animated.Bytes()); !errors.Is(err, ErrAnimated) {
+		t.Errorf("animated GIF: error = %v, want ErrAnimated", err)
+	}
+
+	if _, err := Process([]byte("not an image")); err == nil {
+		t.Error("Process accepted a text file")
+	}
+
+	// Only the header of a decompression bomb: 100000×100000 pixels
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, 100000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	ihdr[8], ihdr[9] = 8, 2 // 8-bit RGB
+	chunk := append([]byte("IHDR"), ihdr...)
+	bomb := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	bomb = append(bomb, chunk...)
+	bomb = binary.BigEndian.AppendUint32(bomb, crc32.ChecksumIEEE(chunk))
+	if _, err := Process(bomb); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("100000×100000 image: error = %v, want it rejected as too large", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package markup renders news content written in Markdown (with an
+// optional limited HTML subset) into sanitized HTML.
+package markup
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Linkify, extension.Strikethrough, extension.Table),
+	goldmark.WithRendererOptions(
+		// Single line breaks are kept, as editors are used to plain text
+		goldmarkhtml.WithHardWraps(),
+		// Raw HTML is passed through and cleaned up by the policy below
+		goldmarkhtml.WithUnsafe(),
+	),
+)
+
+// policy allows the formatting editors need (headings, lists, links,
+// images, tables, quotes) and removes scripts, styles, event handlers and
+// javascript: URLs.
+var policy = newPolicy()
+
+var stripPolicy = bluemonday.StrictPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoReferrerOnFullyQualifiedLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render converts Markdown source into sanitized HTML
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return Sanitize(buf.String()), nil
+}
+
+// Sanitize removes everything from an HTML fragment that is not allowed in news content
+func Sanitize(fragment string) string {
+	return strings.TrimSpace(policy.Sanitize(fragment))
+}
+
+// PlainText strips the tags from rendered HTML and returns its text, e.g.
+// for excerpts and meta descriptions.
+func PlainText(fragment string) string {
+	// Block elements become spaces so words from different paragraphs don't stick together
+	fragment = strings.NewReplacer("</p>", "</p> ", "<br>", " ", "</li>", "</li> ", "</h1>", "</h1> ",
+		"</h2>", "</h2> ", "</h3>", "</h3> ", "</td>", "</td> ").Replace(fragment)
+	text := html.UnescapeString(stripPolicy.Sanitize(fragment))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Excerpt shortens text to at most maxRunes characters, cutting at a word
+// boundary and adding an ellipsis. Whitespace runs become single spaces.
+func Excerpt(text string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+
+	cut := string(runes[:maxRunes])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ".,;:!?-— ") + "…"
+}
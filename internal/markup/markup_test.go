@@ -0,0 +1,103 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{"short text is kept", "День знаний", 20, "День знаний"},
+		{"whitespace is collapsed", "  День\n\tзнаний  ", 20, "День знаний"},
+		{"cut at a word boundary", "Линейка начнется в девять утра", 20, "Линейка начнется в…"},
+		{"punctuation before the cut is dropped", "Линейка, концерт и чаепитие", 9, "Линейка…"},
+		{"one long word is cut inside", "Электрификация", 5, "Элект…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.text, tt.maxRunes); got != tt.want {
+				t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRemovesXSS(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		banned []string
+	}{
+		{"script tag", "Текст<script>alert(1)</script>", []string{"<script", "alert(1)"}},
+		{"event handler", `<img src="/uploads/a.jpg" onerror="alert(1)">`, []string{"onerror"}},
+		{"javascript link in markdown", "[нажми](javascript:alert(1))", []string{"javascript:"}},
+		{"javascript link in html", `<a href="JaVaScRiPt:alert(1)">нажми</a>`, []string{"javascript:", "JaVaScRiPt:"}},
+		{"entity-encoded javascript", `<a href="&#106;avascript:alert(1)">нажми</a>`, []string{"avascript:"}},
+		{"data url", `<a href="data:text/html;base64,PHNjcmlwdD4=">нажми</a>`, []string{"data:"}},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, []string{"<iframe"}},
+		{"style tag", "<style>body{display:none}</style>", []string{"<style", "display:none"}},
+		{"style attribute", `<p style="position:fixed">текст</p>`, []string{"style="}},
+		{"svg onload", `<svg onload="alert(1)"></svg>`, []string{"<svg", "onload"}},
+		{"form", `<form action="https://evil.example"><input name="password"></form>`, []string{"<form", "<input"}},
+		// An unclosed tag stays text, so no element is created from it
+		{"unclosed tag", `<img src=x onerror=alert(1)//`, []string{"<img"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, banned := range tt.banned {
+				if strings.Contains(got, banned) {
+					t.Errorf("Render(%q) = %q, contains %q", tt.source, got, banned)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderKeepsFormatting(t *testing.T) {
+	source := "## Расписание\n\n**Важно:** уроки с *9:00*.\n\n" +
+		"- первый\n- второй\n\n" +
+		"| День | Время |\n|---|---|\n| Пн | 9:00 |\n\n" +
+		"> цитата\n\n" +
+		"![Фото](/uploads/a.jpg)\n\n" +
+		"[Сайт](https://edu.example) и [документы](/documents.html)"
+
+	got, err := Render(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<h2>Расписание</h2>",
+		"<strong>Важно:</strong>",
+		"<em>9:00</em>",
+		"<li>первый</li>",
+		"<td>Пн</td>",
+		"<blockquote>",
+		`<img src="/uploads/a.jpg" alt="Фото">`,
+		// External links open in a new tab without leaking the referrer
+		`<a href="https://edu.example" rel="noreferrer noopener" target="_blank">Сайт</a>`,
+		`<a href="/documents.html">документы</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render result does not contain %s:\n%s", want, got)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	got := PlainText("<h2>Итоги</h2><p>Первое&nbsp;место &amp; грамота</p><ul><li>один</li><li>два</li></ul><script>x()</script>")
+	want := "Итоги Первое место & грамота один два"
+	if got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+}
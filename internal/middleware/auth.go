@@ -1,26 +1,38 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 
+	"school-website/internal/database"
+	"school-website/internal/models"
+
 	"github.com/gorilla/sessions"
 )
 
+type contextKey string
+
+const userContextKey contextKey = "user"
+
 type AuthMiddleware struct {
 	store *sessions.CookieStore
+	db    *database.Database
 }
 
-func NewAuthMiddleware(store *sessions.CookieStore) *AuthMiddleware {
-	return &AuthMiddleware{store: store}
+func NewAuthMiddleware(store *sessions.CookieStore, db *database.Database) *AuthMiddleware {
+	return &AuthMiddleware{store: store, db: db}
 }
 
 func (am *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		session, _ := am.store.Get(r, "session-name")
 
-		// Check if user is authenticated
-		if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
+		// Check if user is authenticated and the account is still active
+		user, ok := am.sessionUser(session)
+		if !ok {
 			// For API requests, return 401 instead of redirect
 			if strings.Contains(r.URL.Path, "/api/") {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -32,6 +44,59 @@ func (am *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		}
 
 		// If authenticated, pass control to the next handler
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireRole allows the request only for users having one of the given
+// roles (administrators are always allowed). It must be used after RequireAuth.
+func (am *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := CurrentUser(r)
+			if user == nil || !user.HasRole(roles...) {
+				username := ""
+				if user != nil {
+					username = user.Username
+				}
+				log.Printf("Access denied for user %q to %s %s", username, r.Method, r.URL.Path)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":   "Forbidden",
+					"message": "You do not have permission to perform this action",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (am *AuthMiddleware) sessionUser(session *sessions.Session) (*models.User, bool) {
+	if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
+		return nil, false
+	}
+
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := am.db.GetUser(userID)
+	if err != nil || !user.Active {
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// CurrentUser returns the authenticated user stored in the request context
+// by RequireAuth, or nil for public requests.
+func CurrentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userContextKey).(*models.User)
+	return user
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate        = "create"
+	AuditUpdate        = "update"
+	AuditDelete        = "delete"
+	AuditEnable        = "enable"
+	AuditDisable       = "disable"
+	AuditChangeRole    = "change_role"
+	AuditResetPassword = "reset_password"
+	AuditRestore       = "restore"
+	AuditRevoke        = "revoke"
+)
+
+// Audited entity types
+const (
+	EntityNews      = "news"
+	EntityDocument  = "document"
+	EntityFolder    = "folder"
+	EntityUser      = "user"
+	EntityTag       = "tag"
+	EntityNewsImage = "news_image"
+	EntityShare     = "document_share"
+)
+
+// AuditEntry represents a single change made in the admin panel
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	UserID     int             `json:"user_id"`
+	Username   string          `json:"username"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter holds optional conditions for browsing the audit log
+type AuditFilter struct {
+	UserID     int
+	Username   string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
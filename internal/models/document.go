@@ -2,17 +2,59 @@ package models
 
 import "time"
 
+// Visibility of documents and folders
+const (
+	VisibilityPublic = "public" // shown to every visitor
+	VisibilityStaff  = "staff"  // shown only to logged-in staff
+)
+
+// IsValidVisibility reports whether visibility is one of the known values
+func IsValidVisibility(visibility string) bool {
+	return visibility == VisibilityPublic || visibility == VisibilityStaff
+}
+
 type Document struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	FileName    string    `json:"file_name"`
-	FilePath    string    `json:"file_path"`
-	FileSize    int64     `json:"file_size"`
-	FileType    string    `json:"file_type"`
-	Category    string    `json:"category"`
-	FolderID    int       `json:"folder_id"`   // Добавлено
-	FolderName  string    `json:"folder_name"` // Добавлено
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	FileName     string    `json:"-"`             // name of the stored file, generated by the server; never sent to clients
+	OriginalName string    `json:"original_name"` // name of the file as uploaded, used for downloads
+	FilePath     string    `json:"-"`             // storage key, e.g. documents/3f9c….pdf; not sent to clients either
+	FileSize     int64     `json:"file_size"`
+	FileType     string    `json:"file_type"`
+	Version      int       `json:"version"`                // number of the current version, starting at 1
+	VersionNote  string    `json:"version_note,omitempty"` // what changed in the current version
+	Category     string    `json:"category"`
+	FolderID     int       `json:"folder_id"`   // Добавлено
+	FolderName   string    `json:"folder_name"` // Добавлено
+	Visibility   string    `json:"visibility"`
+	Public       bool      `json:"public"` // the document and all folders above it are public
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DocumentVersion is one uploaded file of a document. The document itself
+// always points at the file of its latest version.
+type DocumentVersion struct {
+	ID           int       `json:"id"`
+	DocumentID   int       `json:"document_id"`
+	Version      int       `json:"version"`
+	FileName     string    `json:"-"`
+	OriginalName string    `json:"original_name"`
+	FilePath     string    `json:"-"`
+	FileSize     int64     `json:"file_size"`
+	FileType     string    `json:"file_type"`
+	Note         string    `json:"note"`
+	UserID       int       `json:"user_id"`
+	Username     string    `json:"username"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DocumentHit is a document found by a full-text search. TitleHTML and
+// Snippet are HTML with the matched words in <mark> elements.
+type DocumentHit struct {
+	Document
+	TitleHTML string  `json:"title_html"`
+	Snippet   string  `json:"snippet"` // part of the text or the description around the first match
+	Score     float64 `json:"score"`   // relevance, higher is better
 }
@@ -3,9 +3,57 @@ package models
 import "time"
 
 type Folder struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Icon        string    `json:"icon"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            int       `json:"id"`
+	ParentID      int       `json:"parent_id"` // 0 for top-level folders
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	Icon          string    `json:"icon"`
+	SortOrder     int       `json:"sort_order"`     // position among the sibling folders
+	DocumentCount int       `json:"document_count"` // documents directly in the folder
+	TotalSize     int64     `json:"total_size"`     // total size of those documents in bytes
+	Visibility    string    `json:"visibility"`
+	Public        bool      `json:"public"` // the folder and all folders above it are public
+	CreatedAt     time.Time `json:"created_at"`
+	Children      []Folder  `json:"children,omitempty"` // filled only in the folder tree
+}
+
+// Breadcrumb is one step of the path to a folder
+type Breadcrumb struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// FolderTree arranges a flat list of folders into a tree and returns the
+// top-level folders; siblings keep their order from the list. Folders whose
+// parent is not in the list are treated as top-level ones, so nothing is lost
+// if the list is partial.
+func FolderTree(folders []Folder) []Folder {
+	known := make(map[int]bool, len(folders))
+	for _, f := range folders {
+		known[f.ID] = true
+	}
+	children := map[int][]Folder{}
+	for _, f := range folders {
+		parent := f.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], f)
+	}
+
+	var build func(parent int, seen map[int]bool) []Folder
+	build = func(parent int, seen map[int]bool) []Folder {
+		list := children[parent]
+		result := make([]Folder, 0, len(list))
+		for _, f := range list {
+			if seen[f.ID] {
+				continue
+			}
+			seen[f.ID] = true
+			f.Children = build(f.ID, seen)
+			result = append(result, f)
+		}
+		return result
+	}
+	return build(0, map[int]bool{})
 }
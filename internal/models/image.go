@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImageVariant is one resized and re-encoded version of an uploaded photo
+type ImageVariant struct {
+	Size   string `json:"size"` // thumb, card or full
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Type   string `json:"type"` // MIME type, e.g. image/webp
+}
+
+// Srcset builds the value of an HTML srcset attribute from the variants of
+// the given MIME type, e.g. "/uploads/a_thumb.webp 320w, /uploads/a_card.webp 640w".
+func Srcset(variants []ImageVariant, mimeType string) string {
+	var parts []string
+	for _, v := range variants {
+		if v.Type == mimeType {
+			parts = append(parts, fmt.Sprintf("%s %dw", v.URL, v.Width))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FallbackType returns the MIME type of the non-WebP variants (JPEG or PNG)
+func FallbackType(variants []ImageVariant) string {
+	for _, v := range variants {
+		if v.Type != "image/webp" {
+			return v.Type
+		}
+	}
+	return ""
+}
+
+// ImageFiles lists the URLs of an image and all of its variants without duplicates
+func ImageFiles(url string, variants []ImageVariant) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, u := range append([]string{url}, variantURLs(variants)...) {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			files = append(files, u)
+		}
+	}
+	return files
+}
+
+func variantURLs(variants []ImageVariant) []string {
+	urls := make([]string, len(variants))
+	for i, v := range variants {
+		urls[i] = v.URL
+	}
+	return urls
+}
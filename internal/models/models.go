@@ -20,13 +20,67 @@ type ContactEntry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// News article statuses
+const (
+	NewsDraft       = "draft"
+	NewsScheduled   = "scheduled"
+	NewsPublished   = "published"
+	NewsUnpublished = "unpublished"
+)
+
+// IsValidNewsStatus reports whether status is one of the known news statuses
+func IsValidNewsStatus(status string) bool {
+	switch status {
+	case NewsDraft, NewsScheduled, NewsPublished, NewsUnpublished:
+		return true
+	}
+	return false
+}
+
 // NewsArticle represents a single news article
 type NewsArticle struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	ImageURL  string    `json:"image_url"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int            `json:"id"`
+	Slug          string         `json:"slug"`
+	URL           string         `json:"url"`
+	Title         string         `json:"title"`
+	Content       string         `json:"content,omitempty"`      // Markdown source
+	ContentHTML   string         `json:"content_html,omitempty"` // sanitized HTML rendered from Content
+	Excerpt       string         `json:"excerpt,omitempty"`
+	ImageURL      string         `json:"image_url"`
+	ImageVariants []ImageVariant `json:"image_variants,omitempty"` // resized versions of the uploaded image
+	Status        string         `json:"status"`
+	PublishAt     *time.Time     `json:"publish_at,omitempty"`
+	Tags          []Tag          `json:"tags"`
+	Images        []NewsImage    `json:"images,omitempty"` // gallery, only loaded for a single article
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// News list sort orders
+const (
+	NewsSortNewest = "newest"
+	NewsSortOldest = "oldest"
+	NewsSortTitle  = "title"
+)
+
+// NewsFilter holds the conditions for listing news articles
+type NewsFilter struct {
+	PublicOnly  bool       // only published articles whose time has come
+	Query       string     // substring of the title or content
+	From        *time.Time // published at or after
+	To          *time.Time // published before
+	Tag         string     // slug of a tag the article must have
+	Sort        string     // one of the NewsSort* values
+	ExcerptOnly bool       // return a short excerpt instead of the full content
+	Limit       int
+	Offset      int
+}
+
+// IsPublic reports whether the article is visible on the public site at the given time
+func (a *NewsArticle) IsPublic(now time.Time) bool {
+	if a.Status != NewsPublished && a.Status != NewsScheduled {
+		return false
+	}
+	return a.PublishAt == nil || !a.PublishAt.After(now)
 }
 
 // Credentials for parsing JSON during login
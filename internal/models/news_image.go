@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// NewsImage is a photo in the gallery of a news article
+type NewsImage struct {
+	ID        int            `json:"id"`
+	NewsID    int            `json:"news_id"`
+	URL       string         `json:"url"`
+	Variants  []ImageVariant `json:"variants,omitempty"`
+	Caption   string         `json:"caption"`
+	Position  int            `json:"position"`
+	CreatedAt time.Time      `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// NewsRevision is a full snapshot of a news article stored on every change
+type NewsRevision struct {
+	ID            int            `json:"id"`
+	NewsID        int            `json:"news_id"`
+	Number        int            `json:"number"`
+	Title         string         `json:"title"`
+	Slug          string         `json:"slug"`
+	Content       string         `json:"content,omitempty"`
+	ImageURL      string         `json:"image_url"`
+	ImageVariants []ImageVariant `json:"image_variants,omitempty"`
+	Status        string         `json:"status"`
+	PublishAt     *time.Time     `json:"publish_at,omitempty"`
+	Action        string         `json:"action"`
+	RestoredFrom  int            `json:"restored_from,omitempty"`
+	UserID        int            `json:"user_id"`
+	Username      string         `json:"username"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
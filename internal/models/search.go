@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Types of site search results
+const (
+	SearchTypeNews     = "news"
+	SearchTypeDocument = "document"
+	SearchTypeFolder   = "folder"
+)
+
+// SearchTypes lists the result types in the order used for equally relevant results
+var SearchTypes = []string{SearchTypeNews, SearchTypeDocument, SearchTypeFolder}
+
+// SearchTypeLabels are the names of the result types shown to visitors
+var SearchTypeLabels = map[string]string{
+	SearchTypeNews:     "Новость",
+	SearchTypeDocument: "Документ",
+	SearchTypeFolder:   "Папка",
+}
+
+// SearchResult is a news article, document or folder found by the site
+// search. TitleHTML and Snippet are HTML with the matched words in <mark>
+// elements.
+type SearchResult struct {
+	Type      string    `json:"type"`
+	TypeLabel string    `json:"type_label"`
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	TitleHTML string    `json:"title_html"`
+	Snippet   string    `json:"snippet"`
+	URL       string    `json:"url"`   // page of the article, download of the document, folder on the documents page
+	Date      time.Time `json:"date"`  // publication of news, last change of documents, creation of folders
+	Score     float64   `json:"score"` // relevance, higher is better
+}
+
+// SearchFilter selects a page of site search results
+type SearchFilter struct {
+	Terms  []string // see search.Terms
+	Types  []string // empty for all types
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DocumentShare is a signed link that lets anyone holding it download one
+// document, staff-only ones included, until the link expires, runs out of
+// downloads or is revoked
+type DocumentShare struct {
+	ID            int        `json:"id"`
+	DocumentID    int        `json:"document_id"`
+	DocumentTitle string     `json:"document_title"`
+	Note          string     `json:"note"` // whom the link was given to
+	URL           string     `json:"url"`  // address of the link with its signature
+	ExpiresAt     time.Time  `json:"expires_at"`
+	MaxDownloads  int        `json:"max_downloads"` // 0 for no limit
+	Downloads     int        `json:"downloads"`
+	Active        bool       `json:"active"` // the link can still be used
+	UserID        int        `json:"user_id"`
+	Username      string     `json:"username"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Nonce         string     `json:"-"` // random part of the signed data, kept secret
+}
+
+// IsActive reports whether the link can be used at time t
+func (s *DocumentShare) IsActive(t time.Time) bool {
+	return s.RevokedAt == nil && t.Before(s.ExpiresAt) && (s.MaxDownloads == 0 || s.Downloads < s.MaxDownloads)
+}
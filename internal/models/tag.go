@@ -0,0 +1,15 @@
+package models
+
+// Tag classifies news articles, e.g. "Олимпиады" or "Родительские собрания"
+type Tag struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count,omitempty"` // number of articles, filled in tag listings
+}
+
+// TagInput is the request body for creating or renaming a tag
+type TagInput struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
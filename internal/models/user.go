@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Roles of admin panel users
+const (
+	RoleAdministrator   = "administrator"
+	RoleNewsEditor      = "news_editor"
+	RoleDocumentManager = "document_manager"
+	RoleSecretary       = "secretary"
+)
+
+// Roles lists every role that can be assigned to a user
+var Roles = []string{RoleAdministrator, RoleNewsEditor, RoleDocumentManager, RoleSecretary}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// User represents an admin panel account
+type User struct {
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	FullName     string     `json:"full_name"`
+	Role         string     `json:"role"`
+	PasswordHash string     `json:"-"`
+	Active       bool       `json:"active"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// HasRole reports whether the user has one of the given roles.
+// Administrators are allowed everything.
+func (u *User) HasRole(roles ...string) bool {
+	if u.Role == RoleAdministrator {
+		return true
+	}
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions describes which sections of the admin panel the user may manage
+func (u *User) Permissions() map[string]bool {
+	return map[string]bool{
+		"news":         u.HasRole(RoleNewsEditor),
+		"documents":    u.HasRole(RoleDocumentManager),
+		"applications": u.HasRole(RoleSecretary),
+		"users":        u.HasRole(RoleAdministrator),
+	}
+}
+
+// UserInput is used for parsing JSON when creating a user
+type UserInput struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+// RoleChange is used for parsing JSON when changing a user's role
+type RoleChange struct {
+	Role string `json:"role"`
+}
+
+// PasswordReset is used for parsing JSON when resetting a user's password
+type PasswordReset struct {
+	Password string `json:"password"`
+}
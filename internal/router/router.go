@@ -8,58 +8,87 @@ import (
 	"school-website/internal/database"
 	"school-website/internal/handlers"
 	"school-website/internal/middleware"
+	"school-website/internal/models"
 	"school-website/internal/services"
+	"school-website/internal/storage"
 
 	"github.com/gorilla/mux"
 )
 
-func Setup(cfg *config.Config, db *database.Database) *mux.Router {
+func Setup(cfg *config.Config, db *database.Database, files storage.Storage) *mux.Router {
 	r := mux.NewRouter()
 
 	// Initialize services
 	sessionService := services.NewSessionService(cfg.SessionKey)
-	uploadService := services.NewFileUploadService(cfg.UploadDir)
-	documentService := services.NewDocumentService(db, cfg.UploadDir+"/documents")
+	uploadService := services.NewFileUploadService(files, cfg.ImageTypes)
+	documentService := services.NewDocumentService(db, files, cfg.DocumentTypes)
+	userService := services.NewUserService(db)
+	auditService := services.NewAuditService(db, cfg.TrustedProxies)
+	shareService := services.NewShareService(db, cfg.SessionKey)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(sessionService.GetStore(), cfg)
+	authHandler := handlers.NewAuthHandler(sessionService.GetStore(), userService)
 	contactHandler := handlers.NewContactHandler(db)
-	newsHandler := handlers.NewNewsHandler(db, uploadService)
-	documentHandler := handlers.NewDocumentHandler(documentService)
-	folderHandler := handlers.NewFolderHandler(db) // Добавлено
+	newsHandler := handlers.NewNewsHandler(db, uploadService, auditService)
+	newsPageHandler := handlers.NewNewsPageHandler(db, cfg)
+	tagHandler := handlers.NewTagHandler(db, auditService)
+	feedHandler := handlers.NewFeedHandler(db, files, cfg)
+	documentHandler := handlers.NewDocumentHandler(documentService, auditService)
+	folderHandler := handlers.NewFolderHandler(db, auditService) // Добавлено
+	userHandler := handlers.NewUserHandler(userService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	searchHandler := handlers.NewSearchHandler(db)
+	shareHandler := handlers.NewShareHandler(shareService, documentService, auditService, cfg)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(sessionService.GetStore())
+	authMiddleware := middleware.NewAuthMiddleware(sessionService.GetStore(), db)
 
 	// --- Public Routes ---
-	setupPublicRoutes(r, authHandler, contactHandler, newsHandler, documentHandler, folderHandler, cfg)
+	setupPublicRoutes(r, authHandler, contactHandler, newsHandler, newsPageHandler, feedHandler, tagHandler, documentHandler, folderHandler, searchHandler, shareHandler, cfg)
 
 	// --- Protected Admin Routes ---
-	setupAdminRoutes(r, authHandler, contactHandler, newsHandler, documentHandler, folderHandler, authMiddleware, cfg)
+	setupAdminRoutes(r, authHandler, contactHandler, newsHandler, tagHandler, documentHandler, folderHandler, shareHandler, userHandler, auditHandler, authMiddleware, cfg)
 
-	// Public static files (must be last)
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir(cfg.PublicDir)))
+	// Uploaded files come from the file storage, which may not be the local disk.
+	// The prefix is taken for every method so that nothing under it falls
+	// through to the static files below.
+	r.PathPrefix(storage.URLPrefix).Handler(handlers.NewUploadsHandler(files))
+
+	// Public static files (must be last); the upload directory is not served
+	r.PathPrefix("/").Handler(handlers.NewStaticHandler(cfg.PublicDir, cfg.UploadDir))
 
 	return r
 }
 
 func setupPublicRoutes(r *mux.Router, authHandler *handlers.AuthHandler,
 	contactHandler *handlers.ContactHandler, newsHandler *handlers.NewsHandler,
-	documentHandler *handlers.DocumentHandler, folderHandler *handlers.FolderHandler, cfg *config.Config) {
+	newsPageHandler *handlers.NewsPageHandler, feedHandler *handlers.FeedHandler, tagHandler *handlers.TagHandler,
+	documentHandler *handlers.DocumentHandler, folderHandler *handlers.FolderHandler, searchHandler *handlers.SearchHandler,
+	shareHandler *handlers.ShareHandler, cfg *config.Config) {
 
 	// API endpoints
 	r.HandleFunc("/api/contact", contactHandler.SubmitContact).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/news", newsHandler.GetAllNews).Methods("GET")
 	r.HandleFunc("/api/news/{id}", newsHandler.GetSingleNews).Methods("GET")
+	r.HandleFunc("/api/tags", tagHandler.GetTags).Methods("GET")
 
 	// Public document endpoints
 	r.HandleFunc("/api/documents", documentHandler.GetAllDocuments).Methods("GET")
+	r.HandleFunc("/api/documents/search", documentHandler.SearchDocuments).Methods("GET")
 	r.HandleFunc("/api/documents/{id}", documentHandler.GetDocument).Methods("GET")
 	r.HandleFunc("/api/documents/{id}/download", documentHandler.DownloadDocument).Methods("GET")
 
+	// Signed share links, also for staff-only documents
+	r.HandleFunc(services.SharePathPrefix+"{id:[0-9]+}/{signature}", shareHandler.Download).Methods("GET", "HEAD")
+
+	// Site-wide search over news, documents and folders
+	r.HandleFunc("/api/search", searchHandler.Search).Methods("GET")
+
 	// Public folder endpoints
 	r.HandleFunc("/api/folders", folderHandler.GetAllFolders).Methods("GET")
+	r.HandleFunc("/api/folders/tree", folderHandler.GetFolderTree).Methods("GET")
 	r.HandleFunc("/api/folders/{id}/documents", folderHandler.GetFolderDocuments).Methods("GET")
+	r.HandleFunc("/api/folders/{id}/download.zip", documentHandler.DownloadFolder).Methods("GET")
 
 	// Auth endpoints
 	r.HandleFunc("/login", authHandler.Login).Methods("POST")
@@ -69,9 +98,13 @@ func setupPublicRoutes(r *mux.Router, authHandler *handlers.AuthHandler,
 		http.ServeFile(w, r, cfg.TemplatesDir+"/login.html")
 	})
 
-	r.HandleFunc("/news_article.html", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, cfg.PublicDir+"/news_article.html")
-	})
+	// News permalinks; old /news_article.html?id= links redirect to them
+	r.HandleFunc("/news/{slug}", newsPageHandler.ArticlePage).Methods("GET")
+	r.HandleFunc("/news_article.html", newsPageHandler.LegacyArticlePage)
+
+	// News feeds for feed readers
+	r.HandleFunc("/feed.rss", feedHandler.RSS).Methods("GET", "HEAD")
+	r.HandleFunc("/feed.atom", feedHandler.Atom).Methods("GET", "HEAD")
 
 	r.HandleFunc("/documents.html", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, cfg.PublicDir+"/documents.html")
@@ -79,53 +112,112 @@ func setupPublicRoutes(r *mux.Router, authHandler *handlers.AuthHandler,
 }
 
 func setupAdminRoutes(r *mux.Router, authHandler *handlers.AuthHandler,
-	contactHandler *handlers.ContactHandler, newsHandler *handlers.NewsHandler,
-	documentHandler *handlers.DocumentHandler, folderHandler *handlers.FolderHandler,
+	contactHandler *handlers.ContactHandler, newsHandler *handlers.NewsHandler, tagHandler *handlers.TagHandler,
+	documentHandler *handlers.DocumentHandler, folderHandler *handlers.FolderHandler, shareHandler *handlers.ShareHandler,
+	userHandler *handlers.UserHandler, auditHandler *handlers.AuditHandler,
 	authMiddleware *middleware.AuthMiddleware, cfg *config.Config) {
 
 	adminRouter := r.PathPrefix("/admin").Subrouter()
 	adminRouter.Use(authMiddleware.RequireAuth)
 
+	// Role guards (administrators pass every guard)
+	secretaries := authMiddleware.RequireRole(models.RoleSecretary)
+	newsEditors := authMiddleware.RequireRole(models.RoleNewsEditor)
+	documentManagers := authMiddleware.RequireRole(models.RoleDocumentManager)
+	administrators := authMiddleware.RequireRole(models.RoleAdministrator)
+	documentSharers := authMiddleware.RequireRole(models.RoleDocumentManager, models.RoleSecretary)
+
+	// Current user
+	adminRouter.HandleFunc("/api/me", userHandler.GetCurrentUser).Methods("GET")
+
 	// API routes
-	adminRouter.HandleFunc("/api/applications", contactHandler.GetApplications).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/api/contacts", contactHandler.GetApplications).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/api/applications", secretaries(http.HandlerFunc(contactHandler.GetApplications))).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/api/contacts", secretaries(http.HandlerFunc(contactHandler.GetApplications))).Methods("GET", "OPTIONS")
 
 	// News routes
-	adminRouter.HandleFunc("/api/news", newsHandler.CreateNews).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/api/news", newsEditors(http.HandlerFunc(newsHandler.GetAllNews))).Methods("GET")
+	adminRouter.Handle("/api/news", newsEditors(http.HandlerFunc(newsHandler.CreateNews))).Methods("POST", "OPTIONS")
 	adminRouter.HandleFunc("/api/news/{id}", newsHandler.GetSingleNews).Methods("GET")
-	adminRouter.HandleFunc("/api/news/{id}", newsHandler.UpdateNews).Methods("PUT")
-	adminRouter.HandleFunc("/api/news/{id}", newsHandler.DeleteNews).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/api/news/{id}", newsEditors(http.HandlerFunc(newsHandler.UpdateNews))).Methods("PUT")
+	adminRouter.Handle("/api/news/{id}", newsEditors(http.HandlerFunc(newsHandler.DeleteNews))).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/api/news/{id}/status", newsEditors(http.HandlerFunc(newsHandler.UpdateNewsStatus))).Methods("PUT")
+	adminRouter.Handle("/api/news/{id}/revisions", newsEditors(http.HandlerFunc(newsHandler.GetRevisions))).Methods("GET")
+	adminRouter.Handle("/api/news/{id}/revisions/{rev}", newsEditors(http.HandlerFunc(newsHandler.GetRevision))).Methods("GET")
+	adminRouter.Handle("/api/news/{id}/revisions/{rev}/diff", newsEditors(http.HandlerFunc(newsHandler.DiffRevision))).Methods("GET")
+	adminRouter.Handle("/api/news/{id}/revisions/{rev}/restore", newsEditors(http.HandlerFunc(newsHandler.RestoreRevision))).Methods("POST")
+	adminRouter.Handle("/api/news/{id}/images", newsEditors(http.HandlerFunc(newsHandler.UploadNewsImages))).Methods("POST")
+	adminRouter.Handle("/api/news/{id}/images/order", newsEditors(http.HandlerFunc(newsHandler.ReorderNewsImages))).Methods("PUT")
+	adminRouter.Handle("/api/news/{id}/images/{imageId}", newsEditors(http.HandlerFunc(newsHandler.UpdateNewsImage))).Methods("PUT")
+	adminRouter.Handle("/api/news/{id}/images/{imageId}", newsEditors(http.HandlerFunc(newsHandler.DeleteNewsImage))).Methods("DELETE")
+
+	// News tags
+	adminRouter.Handle("/api/tags", newsEditors(http.HandlerFunc(tagHandler.GetTags))).Methods("GET")
+	adminRouter.Handle("/api/tags", newsEditors(http.HandlerFunc(tagHandler.CreateTag))).Methods("POST")
+	adminRouter.Handle("/api/tags/{id}", newsEditors(http.HandlerFunc(tagHandler.UpdateTag))).Methods("PUT")
+	adminRouter.Handle("/api/tags/{id}", newsEditors(http.HandlerFunc(tagHandler.DeleteTag))).Methods("DELETE")
 
 	// Document routes (admin only)
 	adminRouter.HandleFunc("/api/documents", documentHandler.GetAllDocuments).Methods("GET")
-	adminRouter.HandleFunc("/api/documents", documentHandler.UploadDocument).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/api/documents/search", documentHandler.SearchDocuments).Methods("GET")
+	adminRouter.Handle("/api/documents", documentManagers(http.HandlerFunc(documentHandler.UploadDocument))).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/api/documents/move", documentManagers(http.HandlerFunc(documentHandler.MoveDocuments))).Methods("POST")
 	adminRouter.HandleFunc("/api/documents/{id}", documentHandler.GetDocument).Methods("GET")
-	adminRouter.HandleFunc("/api/documents/{id}", documentHandler.DeleteDocument).Methods("DELETE", "OPTIONS")
+	adminRouter.HandleFunc("/api/documents/{id}/download", documentHandler.DownloadDocument).Methods("GET")
+	adminRouter.Handle("/api/documents/{id}", documentManagers(http.HandlerFunc(documentHandler.UpdateDocument))).Methods("PUT", "PATCH")
+	adminRouter.Handle("/api/documents/{id}", documentManagers(http.HandlerFunc(documentHandler.DeleteDocument))).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/api/documents/{id}/versions", documentManagers(http.HandlerFunc(documentHandler.GetVersions))).Methods("GET")
+	adminRouter.Handle("/api/documents/{id}/versions", documentManagers(http.HandlerFunc(documentHandler.UploadVersion))).Methods("POST")
+	adminRouter.Handle("/api/documents/{id}/versions/{version}/download", documentManagers(http.HandlerFunc(documentHandler.DownloadVersion))).Methods("GET")
+	adminRouter.Handle("/api/documents/{id}/shares", documentSharers(http.HandlerFunc(shareHandler.GetDocumentShares))).Methods("GET")
+	adminRouter.Handle("/api/documents/{id}/shares", documentSharers(http.HandlerFunc(shareHandler.CreateShare))).Methods("POST")
+	adminRouter.Handle("/api/shares", documentSharers(http.HandlerFunc(shareHandler.GetShares))).Methods("GET")
+	adminRouter.Handle("/api/shares/{id}", documentSharers(http.HandlerFunc(shareHandler.RevokeShare))).Methods("DELETE")
 
 	// Folder routes (admin only)
 	adminRouter.HandleFunc("/api/folders", folderHandler.GetAllFolders).Methods("GET")
-	adminRouter.HandleFunc("/api/folders", folderHandler.CreateFolder).Methods("POST", "OPTIONS")
-	adminRouter.HandleFunc("/api/folders/{id}", folderHandler.DeleteFolder).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/api/folders", documentManagers(http.HandlerFunc(folderHandler.CreateFolder))).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/api/folders/order", documentManagers(http.HandlerFunc(folderHandler.ReorderFolders))).Methods("PUT")
+	adminRouter.Handle("/api/folders/{id}", documentManagers(http.HandlerFunc(folderHandler.UpdateFolder))).Methods("PUT", "PATCH")
+	adminRouter.Handle("/api/folders/{id}", documentManagers(http.HandlerFunc(folderHandler.DeleteFolder))).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/api/folders/{id}/move", documentManagers(http.HandlerFunc(folderHandler.MoveFolder))).Methods("PUT")
+	adminRouter.HandleFunc("/api/folders/tree", folderHandler.GetFolderTree).Methods("GET")
 	adminRouter.HandleFunc("/api/folders/{id}/documents", folderHandler.GetFolderDocuments).Methods("GET")
+	adminRouter.HandleFunc("/api/folders/{id}/download.zip", documentHandler.DownloadFolder).Methods("GET")
+
+	// User routes (administrators only)
+	adminRouter.Handle("/api/users", administrators(http.HandlerFunc(userHandler.GetAllUsers))).Methods("GET")
+	adminRouter.Handle("/api/users", administrators(http.HandlerFunc(userHandler.CreateUser))).Methods("POST")
+	adminRouter.Handle("/api/users/{id}/disable", administrators(http.HandlerFunc(userHandler.DisableUser))).Methods("POST")
+	adminRouter.Handle("/api/users/{id}/enable", administrators(http.HandlerFunc(userHandler.EnableUser))).Methods("POST")
+	adminRouter.Handle("/api/users/{id}/role", administrators(http.HandlerFunc(userHandler.ChangeRole))).Methods("POST")
+	adminRouter.Handle("/api/users/{id}/reset-password", administrators(http.HandlerFunc(userHandler.ResetPassword))).Methods("POST")
+
+	// Audit log (administrators only)
+	adminRouter.Handle("/api/audit", administrators(http.HandlerFunc(auditHandler.GetAuditLog))).Methods("GET")
 
 	// Logout
 	adminRouter.HandleFunc("/logout", authHandler.Logout).Methods("POST")
 
-	// Static admin pages
-	adminPages := map[string]string{
-		"/dashboard.html":      "dashboard.html",
-		"/applications.html":   "applications.html",
-		"/add_news.html":       "add_news.html",
-		"/news_list.html":      "news_list.html",
-		"/edit_news.html":      "edit_news.html",
-		"/documents_list.html": "documents_list.html",
+	// Admin pages, rendered with the current user's role; nil guard means any logged in user
+	pageHandler := handlers.NewAdminPageHandler(cfg.TemplatesDir)
+	adminPages := map[string]struct {
+		file  string
+		guard func(http.Handler) http.Handler
+	}{
+		"/dashboard.html":      {"dashboard.html", nil},
+		"/applications.html":   {"applications.html", secretaries},
+		"/add_news.html":       {"add_news.html", newsEditors},
+		"/news_list.html":      {"news_list.html", newsEditors},
+		"/edit_news.html":      {"edit_news.html", newsEditors},
+		"/documents_list.html": {"documents_list.html", documentManagers},
 	}
 
-	for route, file := range adminPages {
-		filePath := cfg.TemplatesDir + "/" + file
-		adminRouter.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
-			http.ServeFile(w, r, filePath)
-		})
+	for route, page := range adminPages {
+		var handler http.Handler = pageHandler.Page(page.file)
+		if page.guard != nil {
+			handler = page.guard(handler)
+		}
+		adminRouter.Handle(route, handler)
 	}
 
 	log.Println("Admin routes configured")
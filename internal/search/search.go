@@ -0,0 +1,214 @@
+// Package search turns search queries into terms and marks the terms in
+// snippets of the found text. Matching ignores case and treats ё as е.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxQueryLength is the longest accepted query, in characters
+	MaxQueryLength = 200
+
+	// MaxTerms is the number of words of a query that are searched for
+	MaxTerms = 10
+
+	// StartMark and EndMark surround matches in snippets built by SQLite
+	StartMark = "\x02"
+	EndMark   = "\x03"
+)
+
+// endings are the letters stripped from the end of long words, so that a
+// search for "расписание" also finds "расписания" and "расписанием"
+const endings = "аеиоуыэюяйь"
+
+// Terms splits a query into folded words and strips the inflectional
+// endings of long words. Each term matches words starting with it.
+func Terms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(Fold(query), func(r rune) bool { return !isWordRune(r) }) {
+		term := stem([]rune(word))
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+func stem(word []rune) string {
+	for i := 0; i < 2 && len(word) > 4 && strings.ContainsRune(endings, word[len(word)-1]); i++ {
+		word = word[:len(word)-1]
+	}
+	return string(word)
+}
+
+// FTSQuery builds an SQLite FTS5 query that finds rows containing all terms,
+// each as the start of a word
+func FTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+// Fold lower-cases text and replaces ё with е. Every character is replaced
+// by exactly one, so positions in the folded text match the original.
+func Fold(text string) string {
+	return strings.Map(foldRune, text)
+}
+
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'ё' {
+		return 'е'
+	}
+	return r
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// span is a match in a text, in characters
+type span struct {
+	start, end int
+}
+
+// matches finds the whole words of text that start with one of the terms
+func matches(text []rune, terms [][]rune) []span {
+	var spans []span
+	for i := 0; i < len(text); i++ {
+		if !isWordRune(text[i]) || (i > 0 && isWordRune(text[i-1])) {
+			continue
+		}
+		longest := 0
+		for _, term := range terms {
+			if len(term) > longest && hasPrefixAt(text, i, term) {
+				longest = len(term)
+			}
+		}
+		if longest > 0 {
+			end := i + longest
+			for end < len(text) && isWordRune(text[end]) {
+				end++
+			}
+			spans = append(spans, span{i, end})
+			i = end - 1
+		}
+	}
+	return spans
+}
+
+func hasPrefixAt(text []rune, i int, term []rune) bool {
+	if i+len(term) > len(text) {
+		return false
+	}
+	for j, r := range term {
+		if foldRune(text[i+j]) != r {
+			return false
+		}
+	}
+	return true
+}
+
+func termRunes(terms []string) [][]rune {
+	result := make([][]rune, len(terms))
+	for i, term := range terms {
+		result[i] = []rune(term)
+	}
+	return result
+}
+
+// Count returns how many words of text start with one of the terms
+func Count(text string, terms []string) int {
+	return len(matches([]rune(text), termRunes(terms)))
+}
+
+// ContainsAll reports whether every term starts a word of one of the texts
+func ContainsAll(terms []string, texts ...string) bool {
+	for _, term := range terms {
+		found := false
+		for _, text := range texts {
+			if Count(text, []string{term}) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Snippet returns about width characters of text around the first match as
+// HTML, with the matches in <mark> elements and "…" where text was cut.
+// A width of zero or less keeps the whole text.
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	spans := matches(runes, termRunes(terms))
+
+	start, end := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		if len(spans) > 0 {
+			start = spans[0].start - width/3
+		}
+		if start < 0 {
+			start = 0
+		}
+		end = start + width
+		if end > len(runes) {
+			end = len(runes)
+			start = end - width
+		}
+		// Don't cut words in half
+		for start > 0 && start < end && isWordRune(runes[start-1]) && isWordRune(runes[start]) {
+			start++
+		}
+		for end < len(runes) && end > start && isWordRune(runes[end-1]) && isWordRune(runes[end]) {
+			end--
+		}
+		// and don't leave a space next to "…"
+		for start < end && runes[start] == ' ' {
+			start++
+		}
+		for end > start && runes[end-1] == ' ' {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.start < start || s.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:s.start])))
+		b.WriteString("<mark>" + html.EscapeString(string(runes[s.start:s.end])) + "</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// MarkHTML escapes a snippet built by SQLite and turns StartMark and EndMark
+// into <mark> elements
+func MarkHTML(snippet string) string {
+	snippet = html.EscapeString(strings.Join(strings.Fields(snippet), " "))
+	return strings.NewReplacer(StartMark, "<mark>", EndMark, "</mark>").Replace(snippet)
+}
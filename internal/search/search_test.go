@@ -0,0 +1,105 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Расписание ЗВОНКОВ", []string{"расписан", "звонков"}},
+		{"Ёлка", []string{"елка"}},
+		{"Қазақ тілі", []string{"қазақ", "тілі"}},
+		{"школа школы Школу", []string{"школ"}},
+		{"C++, 2024-й год!", []string{"c", "2024", "й", "год"}},
+		{"мир", []string{"мир"}},
+		{"один два три четыре пять шесть семь восемь девять десять одиннадцать",
+			[]string{"один", "два", "три", "четыр", "пять", "шест", "семь", "восем", "девят", "десят"}},
+		{" ,.! ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{[]string{"расписан"}, `"расписан"*`},
+		{[]string{"расписан", "звонков"}, `"расписан"* "звонков"*`},
+		{[]string{`a"b`}, `"a""b"*`},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := FTSQuery(tt.terms); got != tt.want {
+			t.Errorf("FTSQuery(%q) = %q, want %q", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	tests := []struct {
+		terms []string
+		texts []string
+		want  bool
+	}{
+		{[]string{"расписан", "звонков"}, []string{"Расписание", "ЗВОНКОВ на четверть"}, true},
+		{[]string{"елк"}, []string{"Новогодняя Ёлка"}, true},
+		{[]string{"қазақ"}, []string{"ҚАЗАҚСТАН"}, true},
+		{[]string{"меню"}, []string{"Перемена", "переменю"}, false},
+		{[]string{"расписан", "обед"}, []string{"Расписание"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := ContainsAll(tt.terms, tt.texts...); got != tt.want {
+			t.Errorf("ContainsAll(%q, %q) = %v, want %v", tt.terms, tt.texts, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{"whole text", "Расписание звонков на Ёлку", []string{"расписан", "елк"}, 0,
+			"<mark>Расписание</mark> звонков на <mark>Ёлку</mark>"},
+		{"escapes html", "<b>Меню</b> & столовая", []string{"меню"}, 0,
+			"&lt;b&gt;<mark>Меню</mark>&lt;/b&gt; &amp; столовая"},
+		{"only word starts", "Переменю меню", []string{"меню"}, 0, "Переменю <mark>меню</mark>"},
+		{"longest term wins", "Школьный двор", []string{"школ", "школьн"}, 0, "<mark>Школьный</mark> двор"},
+		{"collapses spaces", "Приказ\n\n  №5", []string{"приказ"}, 0, "<mark>Приказ</mark> №5"},
+		{"cut around match", "один два три четыре пять шесть семь восемь девять десять", []string{"шест"}, 20,
+			"…пять <mark>шесть</mark> семь…"},
+		{"short text not cut", "один два", []string{"два"}, 20, "один <mark>два</mark>"},
+		{"no match keeps start", "один два три четыре пять шесть", []string{"сто"}, 10, "один два…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, tt.width); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkHTML(t *testing.T) {
+	got := MarkHTML("a <b> " + StartMark + "ёлка" + EndMark + "\n end")
+	want := "a &lt;b&gt; <mark>ёлка</mark> end"
+	if got != want {
+		t.Errorf("MarkHTML() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"school-website/internal/database"
+	"school-website/internal/middleware"
+	"school-website/internal/models"
+)
+
+type AuditService struct {
+	db             *database.Database
+	trustedProxies []*net.IPNet
+}
+
+// NewAuditService creates the audit log service. trustedProxies lists the
+// IPs or CIDRs of reverse proxies allowed to report the client address in
+// X-Forwarded-For or X-Real-IP; invalid entries are logged and ignored.
+func NewAuditService(db *database.Database, trustedProxies []string) *AuditService {
+	s := &AuditService{db: db}
+	for _, proxy := range trustedProxies {
+		cidr := proxy
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid trusted proxy %q: %v", proxy, err)
+			continue
+		}
+		s.trustedProxies = append(s.trustedProxies, network)
+	}
+	return s
+}
+
+// Record stores who changed what in the audit log. before and after are
+// snapshots of the entity (nil when it did not exist). Failures are only
+// logged so that auditing never breaks the admin action itself.
+func (s *AuditService) Record(r *http.Request, action, entityType string, entityID interface{}, before, after interface{}) {
+	entry := models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		IP:         s.ClientIP(r),
+	}
+
+	if user := middleware.CurrentUser(r); user != nil {
+		entry.UserID = user.ID
+		entry.Username = user.Username
+	}
+
+	if err := s.db.SaveAuditEntry(entry); err != nil {
+		log.Printf("Warning: failed to write audit entry %s %s %s: %v", action, entityType, entry.EntityID, err)
+	}
+}
+
+func (s *AuditService) GetEntries(filter models.AuditFilter) ([]models.AuditEntry, int, error) {
+	return s.db.GetAuditEntries(filter)
+}
+
+func snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Warning: failed to encode audit snapshot: %v", err)
+		return nil
+	}
+	return data
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For and X-Real-IP
+// are only honoured when the request comes from a trusted proxy, otherwise
+// anyone could put any address into the audit log.
+func (s *AuditService) ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !s.trusted(remote) {
+		return remote
+	}
+
+	// Each proxy appends the address it got the request from, so the client
+	// is the last address that is not one of our proxies
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && !s.trusted(hop) {
+				return hop
+			}
+		}
+		if hop := strings.TrimSpace(hops[0]); hop != "" {
+			return hop
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return remote
+}
+
+func (s *AuditService) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
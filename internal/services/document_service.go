@@ -1,80 +1,213 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"school-website/internal/database"
+	"school-website/internal/doctext"
+	"school-website/internal/filetype"
 	"school-website/internal/models"
+	"school-website/internal/search"
+	"school-website/internal/storage"
 )
 
+// DocumentsPrefix is the folder of document files in the storage
+const DocumentsPrefix = "documents/"
+
+// ErrFolderNotFound is returned when documents are put into a folder that
+// doesn't exist
+var ErrFolderNotFound = errors.New("folder not found")
+
 type DocumentService struct {
-	db         *database.Database
-	uploadPath string
+	db           *database.Database
+	files        storage.Storage
+	allowedTypes []string // MIME types detected from the content, see filetype.Check
 }
 
-func NewDocumentService(db *database.Database, uploadPath string) *DocumentService {
-	// Ensure upload directory exists
-	if err := os.MkdirAll(uploadPath, 0755); err != nil {
-		fmt.Printf("Warning: failed to create upload directory %s: %v\n", uploadPath, err)
-	}
-
+func NewDocumentService(db *database.Database, files storage.Storage, allowedTypes []string) *DocumentService {
 	return &DocumentService{
-		db:         db,
-		uploadPath: uploadPath,
+		db:           db,
+		files:        files,
+		allowedTypes: allowedTypes,
 	}
 }
 
-func (s *DocumentService) UploadDocument(title, description, category string, folderID int, file multipart.File, fileHeader *multipart.FileHeader) (*models.Document, error) {
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	fileName := fmt.Sprintf("%d_%s", timestamp, fileHeader.Filename)
-	filePath := filepath.Join(s.uploadPath, fileName)
+// storedFile is a document file saved to the storage under a random name
+type storedFile struct {
+	fileName, filePath, fileType string
+	text                         string // extracted for the search index, empty if it failed
+}
 
-	// Create file
-	dst, err := os.Create(filePath)
+// storeFile checks the type of an uploaded file and saves it to the storage.
+// The file is stored under a random name; the client's name is kept only as
+// metadata for downloads.
+func (s *DocumentService) storeFile(file multipart.File, fileHeader *multipart.FileHeader) (storedFile, error) {
+	// Store the type detected from the content, not the one sent by the browser
+	fileType, err := filetype.Check(file, fileHeader.Size, fileHeader.Filename, s.allowedTypes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %v", err)
+		return storedFile{}, err
+	}
+
+	baseName, err := storage.NewName()
+	if err != nil {
+		return storedFile{}, err
+	}
+	ext := filetype.Extension(fileType)
+	if ext == "" {
+		ext = ".bin"
 	}
-	defer dst.Close()
+	fileName := baseName + ext
+	filePath := DocumentsPrefix + fileName
 
-	// Copy uploaded file to destination
-	if _, err := io.Copy(dst, file); err != nil {
-		os.Remove(filePath) // Clean up on error
-		return nil, fmt.Errorf("failed to save file: %v", err)
+	// A document that can't be read is still stored, it is only not found by its text
+	var text string
+	if doctext.Supported(fileType) {
+		if text, err = doctext.Extract(file, fileHeader.Size, fileType); err != nil {
+			log.Printf("Warning: failed to extract text of %s: %v", fileHeader.Filename, err)
+		}
+	}
+
+	// Save the file to storage
+	if err := s.files.Put(filePath, file, fileHeader.Size, fileType); err != nil {
+		return storedFile{}, err
+	}
+	return storedFile{fileName: fileName, filePath: filePath, fileType: fileType, text: text}, nil
+}
+
+// UploadDocument creates a document from an uploaded file and returns it as
+// stored. The file becomes version 1 of the document; author may be nil.
+func (s *DocumentService) UploadDocument(title, description, category, visibility string, folderID int, file multipart.File, fileHeader *multipart.FileHeader, author *models.User) (*models.Document, error) {
+	if err := s.CheckFolder(folderID); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storeFile(file, fileHeader)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create document model
 	doc := models.Document{
-		Title:       title,
-		Description: description,
-		FileName:    fileName,
-		FilePath:    filePath,
-		FileSize:    fileHeader.Size,
-		FileType:    fileHeader.Header.Get("Content-Type"),
-		Category:    category,
-		FolderID:    folderID, // Добавлено
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Title:        title,
+		Description:  description,
+		FileName:     stored.fileName,
+		OriginalName: storage.CleanName(fileHeader.Filename),
+		FilePath:     stored.filePath,
+		FileSize:     fileHeader.Size,
+		FileType:     stored.fileType,
+		Version:      1,
+		Category:     category,
+		FolderID:     folderID, // Добавлено
+		Visibility:   visibility,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
 	// Save to database
 	id, err := s.db.SaveDocument(doc)
 	if err != nil {
-		os.Remove(filePath) // Clean up file if database save fails
+		s.files.Delete(stored.filePath) // Clean up file if database save fails
 		return nil, fmt.Errorf("failed to save document to database: %v", err)
 	}
-
 	doc.ID = int(id)
-	return &doc, nil
+
+	// The history starts with the first file; a missing entry is added again
+	// at the next start by the migration, so a failure is only logged
+	if _, err := s.db.SaveDocumentVersion(newVersion(&doc, "", author)); err != nil {
+		log.Printf("Warning: failed to save first version of document %d: %v", doc.ID, err)
+	}
+	s.saveText(doc.ID, stored.text)
+
+	// Folder name and effective visibility come from the stored row
+	return s.GetDocument(strconv.Itoa(doc.ID))
 }
 
-func (s *DocumentService) GetAllDocuments() ([]models.Document, error) {
-	return s.db.GetDocuments()
+// UploadVersion replaces the file of a document with a new version and
+// returns the document as stored. Earlier versions are kept, and the
+// document's download URL serves the new file.
+func (s *DocumentService) UploadVersion(doc *models.Document, note string, file multipart.File, fileHeader *multipart.FileHeader, author *models.User) (*models.Document, error) {
+	stored, err := s.storeFile(file, fileHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *doc
+	updated.FileName = stored.fileName
+	updated.OriginalName = storage.CleanName(fileHeader.Filename)
+	updated.FilePath = stored.filePath
+	updated.FileSize = fileHeader.Size
+	updated.FileType = stored.fileType
+
+	if _, err := s.db.SaveDocumentVersion(newVersion(&updated, note, author)); err != nil {
+		s.files.Delete(stored.filePath)
+		return nil, fmt.Errorf("failed to save document version: %v", err)
+	}
+
+	s.saveText(updated.ID, stored.text)
+
+	return s.GetDocument(strconv.Itoa(updated.ID))
+}
+
+// saveText stores the text of a document's current file for the search.
+// Documents without a saved text are read again at the next start, so a
+// failure is only logged.
+func (s *DocumentService) saveText(documentID int, text string) {
+	if err := s.db.SaveDocumentText(documentID, text); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// newVersion describes the current file of a document as a version
+func newVersion(doc *models.Document, note string, author *models.User) models.DocumentVersion {
+	v := models.DocumentVersion{
+		DocumentID:   doc.ID,
+		FileName:     doc.FileName,
+		OriginalName: doc.OriginalName,
+		FilePath:     doc.FilePath,
+		FileSize:     doc.FileSize,
+		FileType:     doc.FileType,
+		Note:         note,
+	}
+	if author != nil {
+		v.UserID, v.Username = author.ID, author.Username
+	}
+	return v
+}
+
+// GetVersions lists the versions of a document, newest first
+func (s *DocumentService) GetVersions(documentID string) ([]models.DocumentVersion, error) {
+	return s.db.GetDocumentVersions(documentID)
+}
+
+// GetVersion returns a single version of a document
+func (s *DocumentService) GetVersion(documentID string, version int) (*models.DocumentVersion, error) {
+	v, err := s.db.GetDocumentVersion(documentID, version)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// OpenVersion opens the stored file of a document version for reading
+func (s *DocumentService) OpenVersion(v *models.DocumentVersion) (*storage.Object, error) {
+	return s.files.Open(v.FilePath)
+}
+
+// OpenDocument opens the stored file of a document for reading
+func (s *DocumentService) OpenDocument(doc *models.Document) (*storage.Object, error) {
+	return s.files.Open(doc.FilePath)
+}
+
+// GetAllDocuments lists all documents or, with publicOnly, the ones visitors may see
+func (s *DocumentService) GetAllDocuments(publicOnly bool) ([]models.Document, error) {
+	return s.db.GetDocuments(publicOnly)
 }
 
 func (s *DocumentService) GetDocument(id string) (*models.Document, error) {
@@ -85,8 +218,51 @@ func (s *DocumentService) GetDocument(id string) (*models.Document, error) {
 	return &doc, nil
 }
 
-func (s *DocumentService) GetDocumentsByCategory(category string) ([]models.Document, error) {
-	return s.db.GetDocumentsByCategory(category)
+// SearchDocuments finds documents by words of their title, description or
+// text, the most relevant first, and returns one page of hits and the total.
+// With publicOnly, staff-only documents are skipped.
+func (s *DocumentService) SearchDocuments(query string, publicOnly bool, limit, offset int) ([]models.DocumentHit, int, error) {
+	return s.db.SearchDocuments(search.Terms(query), publicOnly, limit, offset)
+}
+
+func (s *DocumentService) GetDocumentsByCategory(category string, publicOnly bool) ([]models.Document, error) {
+	return s.db.GetDocumentsByCategory(category, publicOnly)
+}
+
+// UpdateDocument saves the edited metadata of a document and returns it as
+// stored. The file and its versions are not touched.
+func (s *DocumentService) UpdateDocument(doc *models.Document) (*models.Document, error) {
+	if err := s.CheckFolder(doc.FolderID); err != nil {
+		return nil, err
+	}
+	if err := s.db.UpdateDocument(*doc); err != nil {
+		return nil, err
+	}
+	return s.GetDocument(strconv.Itoa(doc.ID))
+}
+
+// MoveDocuments puts the documents into a folder, or out of any folder if
+// folderID is zero
+func (s *DocumentService) MoveDocuments(ids []int, folderID int) error {
+	if err := s.CheckFolder(folderID); err != nil {
+		return err
+	}
+	return s.db.MoveDocuments(ids, folderID)
+}
+
+// CheckFolder returns an ErrFolderNotFound error unless folderID is zero
+// (no folder) or an existing folder
+func (s *DocumentService) CheckFolder(folderID int) error {
+	if folderID == 0 {
+		return nil
+	}
+	if _, err := s.db.GetFolder(strconv.Itoa(folderID)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("%w: %d", ErrFolderNotFound, folderID)
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *DocumentService) DeleteDocument(id string) error {
@@ -95,7 +271,7 @@ func (s *DocumentService) DeleteDocument(id string) error {
 
 // UploadMultipleDocuments uploads multiple files with the same metadata
 // If title is empty, uses filename (without extension) as title for each file
-func (s *DocumentService) UploadMultipleDocuments(title, description, category string, folderID int, files []*multipart.FileHeader) ([]*models.Document, []error) {
+func (s *DocumentService) UploadMultipleDocuments(title, description, category, visibility string, folderID int, files []*multipart.FileHeader, author *models.User) ([]*models.Document, []error) {
 	var documents []*models.Document
 	var errors []error
 
@@ -121,11 +297,11 @@ func (s *DocumentService) UploadMultipleDocuments(title, description, category s
 		}
 
 		// Upload single document
-		doc, err := s.UploadDocument(fileTitle, description, category, folderID, file, fileHeader)
+		doc, err := s.UploadDocument(fileTitle, description, category, visibility, folderID, file, fileHeader, author)
 		file.Close()
 
 		if err != nil {
-			errors = append(errors, fmt.Errorf("file %d (%s): %v", i+1, fileHeader.Filename, err))
+			errors = append(errors, fmt.Errorf("file %d (%s): %w", i+1, fileHeader.Filename, err))
 			continue
 		}
 
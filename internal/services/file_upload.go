@@ -1,76 +1,154 @@
 package services
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
+
+	"school-website/internal/filetype"
+	"school-website/internal/imaging"
+	"school-website/internal/models"
+	"school-website/internal/storage"
 )
 
 type FileUploadService struct {
-	uploadDir string
+	files        storage.Storage
+	allowedTypes []string // MIME types detected from the content, see filetype.Check
+}
+
+func NewFileUploadService(files storage.Storage, allowedTypes []string) *FileUploadService {
+	return &FileUploadService{files: files, allowedTypes: allowedTypes}
+}
+
+// UploadedImage is a saved image: the URL of its largest variant in the
+// original format (JPEG or PNG) and all of its resized variants
+type UploadedImage struct {
+	URL      string
+	Variants []models.ImageVariant
 }
 
-func NewFileUploadService(uploadDir string) *FileUploadService {
-	return &FileUploadService{uploadDir: uploadDir}
+// Files lists the URLs of every file written for the image
+func (img UploadedImage) Files() []string {
+	return models.ImageFiles(img.URL, img.Variants)
 }
 
-func (s *FileUploadService) HandleFileUpload(r *http.Request) (string, error) {
+func (s *FileUploadService) HandleFileUpload(r *http.Request) (UploadedImage, error) {
 	file, handler, err := r.FormFile("image")
 	if err != nil {
 		if err == http.ErrMissingFile {
-			return "", nil // File was not uploaded, not an error
+			return UploadedImage{}, nil // File was not uploaded, not an error
 		}
 		log.Printf("Error getting file from form: %v", err)
-		return "", err
+		return UploadedImage{}, err
 	}
 	defer file.Close()
 
-	// Validate file extension (basic validation)
-	ext := strings.ToLower(filepath.Ext(handler.Filename))
-	allowedExts := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
-		".webp": true,
+	return s.SaveImage(handler)
+}
+
+// HandleMultipleUploads saves every image sent in the given multipart form
+// field and returns them in upload order. If one of the files fails, the
+// ones already saved are removed again.
+func (s *FileUploadService) HandleMultipleUploads(r *http.Request, field string) ([]UploadedImage, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File[field]) == 0 {
+		return nil, nil
 	}
 
-	if !allowedExts[ext] {
-		return "", fmt.Errorf("invalid file type: %s", ext)
+	var images []UploadedImage
+	for _, handler := range r.MultipartForm.File[field] {
+		img, err := s.SaveImage(handler)
+		if err != nil {
+			s.RemoveImages(images)
+			return nil, fmt.Errorf("%s: %w", handler.Filename, err)
+		}
+		images = append(images, img)
 	}
+	return images, nil
+}
 
-	// Create unique filename
-	uniqueFileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), handler.Filename)
-	uniqueFileName = strings.ReplaceAll(uniqueFileName, " ", "_")
+// RemoveImages deletes previously saved uploads with all their variants
+func (s *FileUploadService) RemoveImages(images []UploadedImage) {
+	for _, img := range images {
+		for _, url := range img.Files() {
+			key, ok := storage.KeyFromURL(url)
+			if !ok {
+				continue
+			}
+			if err := s.files.Delete(key); err != nil {
+				log.Printf("Warning: failed to delete file %s: %v", key, err)
+			}
+		}
+	}
+}
 
-	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
-		log.Printf("Error creating upload directory: %v", err)
-		return "", fmt.Errorf("failed to create upload directory: %v", err)
+// SaveImage stores an uploaded image in the file storage. The photo is
+// rotated according to its EXIF orientation, stripped of metadata and saved
+// in several widths, each also as WebP. Animated GIFs are stored unchanged.
+func (s *FileUploadService) SaveImage(handler *multipart.FileHeader) (UploadedImage, error) {
+	file, err := handler.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded file: %v", err)
+		return UploadedImage{}, err
 	}
+	defer file.Close()
 
-	filePath := filepath.Join(s.uploadDir, uniqueFileName)
-	log.Printf("Attempting to save file: %s", filePath)
+	// The file name and the browser's Content-Type can't be trusted, check
+	// what the file actually contains
+	if _, err := filetype.Check(file, handler.Size, handler.Filename, s.allowedTypes); err != nil {
+		log.Printf("Rejected upload %s: %v", handler.Filename, err)
+		return UploadedImage{}, err
+	}
 
-	// Create file on server
-	dst, err := os.Create(filePath)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		log.Printf("Error creating file %s: %v", filePath, err)
-		return "", fmt.Errorf("failed to create file: %v", err)
+		log.Printf("Error reading uploaded file: %v", err)
+		return UploadedImage{}, fmt.Errorf("failed to read file: %v", err)
 	}
-	defer dst.Close()
 
-	// Copy uploaded file content
-	if _, err := io.Copy(dst, file); err != nil {
-		log.Printf("Error copying file content: %v", err)
-		return "", fmt.Errorf("failed to save file: %v", err)
+	// Common random prefix of all files of this upload
+	baseName, err := storage.NewName()
+	if err != nil {
+		return UploadedImage{}, err
+	}
+
+	variants, err := imaging.Process(data)
+	if err == imaging.ErrAnimated {
+		url, err := s.writeFile(baseName+".gif", data, "image/gif")
+		return UploadedImage{URL: url}, err
+	}
+	if err != nil {
+		return UploadedImage{}, err
+	}
+
+	var saved UploadedImage
+	for _, v := range variants {
+		url, err := s.writeFile(fmt.Sprintf("%s_%s%s", baseName, v.Size, v.Ext), v.Data, v.Type)
+		if err != nil {
+			s.RemoveImages([]UploadedImage{saved})
+			return UploadedImage{}, err
+		}
+		saved.Variants = append(saved.Variants, models.ImageVariant{
+			Size: v.Size, URL: url, Width: v.Width, Height: v.Height, Type: v.Type,
+		})
+		// Variants come from small to large, so the last one in the
+		// original format is the full-size image
+		if v.Type != "image/webp" {
+			saved.URL = url
+		}
 	}
 
-	finalURL := "/uploads/" + uniqueFileName
-	log.Printf("File successfully saved: %s -> URL: %s", filePath, finalURL)
-	return finalURL, nil
+	log.Printf("Image %s saved in %d variants -> URL: %s", handler.Filename, len(saved.Variants), saved.URL)
+	return saved, nil
+}
+
+// writeFile stores a file under the given key and returns its URL
+func (s *FileUploadService) writeFile(key string, data []byte, contentType string) (string, error) {
+	if err := s.files.Put(key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		log.Printf("Error saving file %s: %v", key, err)
+		return "", fmt.Errorf("failed to save file: %v", err)
+	}
+	return storage.URL(key), nil
 }
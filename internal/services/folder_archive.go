@@ -0,0 +1,165 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"school-website/internal/models"
+	"school-website/internal/storage"
+)
+
+// ArchiveEntry is a document put into a folder archive under Path, e.g.
+// "5 класс/Расписание.pdf"
+type ArchiveEntry struct {
+	Path     string
+	Document models.Document
+}
+
+// storedTypes are already compressed; deflating them again only costs time
+var storedTypes = map[string]bool{
+	"application/zip": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// GetFolder returns a folder; with publicOnly a staff-only folder is
+// reported as not found
+func (s *DocumentService) GetFolder(id string, publicOnly bool) (*models.Folder, error) {
+	var folder models.Folder
+	var err error
+	if publicOnly {
+		folder, err = s.db.GetPublicFolder(id)
+	} else {
+		folder, err = s.db.GetFolder(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// FolderArchiveEntries lists the documents of a folder and of all folders
+// below it, each sub-folder becoming a directory of the archive. Documents
+// are named by their original file names; clashing names get a number, as
+// in "Приказ (2).pdf". With publicOnly, staff-only folders and documents
+// are left out.
+func (s *DocumentService) FolderArchiveEntries(folderID string, publicOnly bool) ([]ArchiveEntry, error) {
+	var entries []ArchiveEntry
+	seen := map[string]bool{}
+
+	var walk func(id, dir string) error
+	walk = func(id, dir string) error {
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+
+		documents, err := s.db.GetDocumentsByFolder(id, publicOnly)
+		if err != nil {
+			return err
+		}
+		taken := map[string]bool{}
+		for _, doc := range documents {
+			name := archiveName(doc.OriginalName, "document-"+strconv.Itoa(doc.ID))
+			entries = append(entries, ArchiveEntry{Path: dir + uniqueName(name, taken), Document: doc})
+		}
+
+		subfolders, err := s.db.GetSubfolders(id, publicOnly)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subfolders {
+			name := uniqueName(archiveName(sub.Name, "folder-"+strconv.Itoa(sub.ID)), taken)
+			if err := walk(strconv.Itoa(sub.ID), dir+name+"/"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(folderID, ""); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// archiveName makes a name usable as one path element of a ZIP archive
+func archiveName(name, fallback string) string {
+	name = strings.TrimRight(storage.CleanName(strings.ReplaceAll(name, "/", "_")), ". ")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// uniqueName numbers a name already taken in the same directory. Names are
+// compared case-insensitively, as Windows would when unpacking.
+func uniqueName(name string, taken map[string]bool) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	taken[strings.ToLower(name)] = true
+	return name
+}
+
+// WriteArchive streams the files of the entries to w as a ZIP archive, one
+// file at a time, so the archive is never held in memory. Non-ASCII names
+// are stored as UTF-8 and marked so, which keeps Cyrillic readable on
+// unpacking. A file missing from the storage is skipped; any other error
+// aborts the archive, which the client then sees as broken.
+func (s *DocumentService) WriteArchive(w io.Writer, entries []ArchiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		if err := s.writeArchiveEntry(zw, entry); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (s *DocumentService) writeArchiveEntry(zw *zip.Writer, entry ArchiveEntry) error {
+	file, err := s.files.Open(entry.Document.FilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("Warning: file of document %d is missing, not added to the archive", entry.Document.ID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening file of document %d: %v", entry.Document.ID, err)
+	}
+	defer file.Close()
+
+	header := &zip.FileHeader{
+		Name:     entry.Path,
+		Method:   zip.Deflate,
+		Modified: entry.Document.UpdatedAt,
+	}
+	if storedTypes[entry.Document.FileType] {
+		header.Method = zip.Store
+	}
+
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		return fmt.Errorf("error adding document %d to the archive: %v", entry.Document.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"school-website/internal/database"
+	"school-website/internal/models"
+)
+
+// SharePathPrefix is the path under which share links are served
+const SharePathPrefix = "/share/"
+
+// Errors of share links. A link with a wrong signature is reported as not
+// found, so that guessing reveals nothing about existing links.
+var (
+	ErrShareNotFound = errors.New("share link not found")
+	ErrShareInactive = errors.New("share link is expired, revoked or used up")
+)
+
+// ShareService creates and checks signed links that let people without an
+// account download a document
+type ShareService struct {
+	db  *database.Database
+	key []byte // signing key, derived from the session key
+}
+
+func NewShareService(db *database.Database, sessionKey []byte) *ShareService {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte("document share links"))
+	return &ShareService{db: db, key: mac.Sum(nil)}
+}
+
+// sign computes the signature of a link. It covers the document, the expiry
+// and the download limit, so a link can't be altered, and a random nonce
+// kept in the database, so links can't be forged with the key alone.
+func (s *ShareService) sign(share *models.DocumentShare) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%d:%d:%d:%d:%s", share.ID, share.DocumentID, share.ExpiresAt.Unix(), share.MaxDownloads, share.Nonce)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+// complete fills the fields computed from the stored ones
+func (s *ShareService) complete(share *models.DocumentShare) {
+	share.URL = SharePathPrefix + strconv.Itoa(share.ID) + "/" + s.sign(share)
+	share.Active = share.IsActive(time.Now())
+}
+
+// CreateShare makes a link to a document valid for ttl and, if maxDownloads
+// is positive, for that many downloads. author may be nil.
+func (s *ShareService) CreateShare(doc *models.Document, ttl time.Duration, maxDownloads int, note string, author *models.User) (*models.DocumentShare, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate share link: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	share := models.DocumentShare{
+		DocumentID:   doc.ID,
+		Nonce:        hex.EncodeToString(buf),
+		Note:         note,
+		ExpiresAt:    now.Add(ttl),
+		MaxDownloads: maxDownloads,
+		CreatedAt:    now,
+	}
+	if author != nil {
+		share.UserID, share.Username = author.ID, author.Username
+	}
+
+	id, err := s.db.CreateDocumentShare(share)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetShare(strconv.FormatInt(id, 10))
+}
+
+func (s *ShareService) GetShare(id string) (*models.DocumentShare, error) {
+	share, err := s.db.GetDocumentShare(id)
+	if err != nil {
+		return nil, err
+	}
+	s.complete(&share)
+	return &share, nil
+}
+
+// GetShares lists the links of a document, or of all documents if
+// documentID is empty; with activeOnly only the links that still work
+func (s *ShareService) GetShares(documentID string, activeOnly bool) ([]models.DocumentShare, error) {
+	shares, err := s.db.GetDocumentShares(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.DocumentShare{}
+	for _, share := range shares {
+		s.complete(&share)
+		if activeOnly && !share.Active {
+			continue
+		}
+		result = append(result, share)
+	}
+	return result, nil
+}
+
+func (s *ShareService) RevokeShare(id string) error {
+	return s.db.RevokeDocumentShare(id)
+}
+
+// OpenShare checks a link from a share URL: ErrShareNotFound if the link
+// doesn't exist or its signature is wrong, ErrShareInactive if it can no
+// longer be used
+func (s *ShareService) OpenShare(id, signature string) (*models.DocumentShare, error) {
+	share, err := s.db.GetDocumentShare(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkShare(&share, signature, time.Now()); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// checkShare verifies the signature of a link read from the database and
+// that the link can still be used at now
+func (s *ShareService) checkShare(share *models.DocumentShare, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(share))) {
+		return ErrShareNotFound
+	}
+
+	s.complete(share)
+	if !share.IsActive(now) {
+		return ErrShareInactive
+	}
+	return nil
+}
+
+// CountDownload counts one download through a link, or returns
+// ErrShareInactive if the link was used up or revoked in the meantime
+func (s *ShareService) CountDownload(share *models.DocumentShare) error {
+	counted, err := s.db.CountShareDownload(share.ID)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return ErrShareInactive
+	}
+	share.Downloads++
+	return nil
+}
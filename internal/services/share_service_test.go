@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"school-website/internal/models"
+)
+
+func TestShareSignature(t *testing.T) {
+	s := NewShareService(nil, []byte("test session key"))
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	base := models.DocumentShare{ID: 7, DocumentID: 3, ExpiresAt: expires, MaxDownloads: 5, Nonce: "abc"}
+	signature := s.sign(&base)
+
+	if len(signature) != 24 {
+		t.Errorf("signature %q has %d characters, want 24", signature, len(signature))
+	}
+	if strings.ContainsAny(signature, "+/=") {
+		t.Errorf("signature %q is not URL-safe", signature)
+	}
+
+	other := NewShareService(nil, []byte("another session key"))
+	if other.sign(&base) == signature {
+		t.Error("signature does not depend on the session key")
+	}
+
+	tests := []struct {
+		name   string
+		change func(*models.DocumentShare)
+	}{
+		{"id", func(sh *models.DocumentShare) { sh.ID = 8 }},
+		{"document", func(sh *models.DocumentShare) { sh.DocumentID = 4 }},
+		{"expiry", func(sh *models.DocumentShare) { sh.ExpiresAt = sh.ExpiresAt.Add(time.Second) }},
+		{"download limit", func(sh *models.DocumentShare) { sh.MaxDownloads = 0 }},
+		{"nonce", func(sh *models.DocumentShare) { sh.Nonce = "abd" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := base
+			tt.change(&share)
+			if s.sign(&share) == signature {
+				t.Errorf("changing the %s keeps the signature", tt.name)
+			}
+		})
+	}
+}
+
+func TestCheckShare(t *testing.T) {
+	s := NewShareService(nil, []byte("test session key"))
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	revoked := now.Add(-time.Hour)
+
+	link := func(sh models.DocumentShare) models.DocumentShare {
+		sh.ID, sh.DocumentID, sh.Nonce = 7, 3, "abc"
+		if sh.ExpiresAt.IsZero() {
+			sh.ExpiresAt = now.Add(time.Hour)
+		}
+		return sh
+	}
+
+	tests := []struct {
+		name      string
+		share     models.DocumentShare
+		signature func(valid string) string
+		want      error
+	}{
+		{"valid", link(models.DocumentShare{}), nil, nil},
+		{"valid with downloads left", link(models.DocumentShare{MaxDownloads: 2, Downloads: 1}), nil, nil},
+		{"empty signature", link(models.DocumentShare{}), func(string) string { return "" }, ErrShareNotFound},
+		{"tampered signature", link(models.DocumentShare{}), func(v string) string { return tamper(v) }, ErrShareNotFound},
+		{"truncated signature", link(models.DocumentShare{}), func(v string) string { return v[:len(v)-1] }, ErrShareNotFound},
+		{"expired", link(models.DocumentShare{ExpiresAt: now.Add(-time.Second)}), nil, ErrShareInactive},
+		{"expires now", link(models.DocumentShare{ExpiresAt: now}), nil, ErrShareInactive},
+		{"revoked", link(models.DocumentShare{RevokedAt: &revoked}), nil, ErrShareInactive},
+		{"used up", link(models.DocumentShare{MaxDownloads: 2, Downloads: 2}), nil, ErrShareInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := tt.share
+			signature := s.sign(&share)
+			if tt.signature != nil {
+				signature = tt.signature(signature)
+			}
+
+			err := s.checkShare(&share, signature, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("checkShare() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && share.URL != SharePathPrefix+strconv.Itoa(share.ID)+"/"+signature {
+				t.Errorf("URL = %q, want the signed link", share.URL)
+			}
+		})
+	}
+}
+
+func TestCheckShareOtherKey(t *testing.T) {
+	share := models.DocumentShare{ID: 7, DocumentID: 3, ExpiresAt: time.Now().Add(time.Hour), Nonce: "abc"}
+	signature := NewShareService(nil, []byte("old session key")).sign(&share)
+
+	s := NewShareService(nil, []byte("new session key"))
+	if err := s.checkShare(&share, signature, time.Now()); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("checkShare() with a link signed by another key: error = %v, want %v", err, ErrShareNotFound)
+	}
+}
+
+// tamper changes the first character of a signature
+func tamper(signature string) string {
+	if signature[0] == 'A' {
+		return "B" + signature[1:]
+	}
+	return "A" + signature[1:]
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"school-website/internal/database"
+	"school-website/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUserDisabled       = errors.New("user account is disabled")
+	ErrWeakPassword       = fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	ErrInvalidRole        = fmt.Errorf("role must be one of: %s", strings.Join(models.Roles, ", "))
+)
+
+type UserService struct {
+	db *database.Database
+}
+
+func NewUserService(db *database.Database) *UserService {
+	return &UserService{db: db}
+}
+
+// EnsureBootstrapUser creates the first admin account from the given
+// credentials when the users table is empty. Once at least one user
+// exists the credentials are ignored.
+func (s *UserService) EnsureBootstrapUser(username, password string) error {
+	count, err := s.db.CountUsers()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	bootstrap := models.UserInput{Username: username, Password: password, Role: models.RoleAdministrator}
+	if _, err := s.CreateUser(bootstrap); err != nil {
+		return fmt.Errorf("failed to create bootstrap user: %v", err)
+	}
+
+	log.Printf("Bootstrap admin user %s created. Change its password after the first login!", username)
+	return nil
+}
+
+func (s *UserService) Authenticate(username, password string) (*models.User, error) {
+	user, err := s.db.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		// Compare against a dummy hash so that unknown usernames take as long as known ones
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.Active {
+		return nil, ErrUserDisabled
+	}
+
+	if err := s.db.TouchUserLogin(user.ID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	return &user, nil
+}
+
+func (s *UserService) CreateUser(input models.UserInput) (*models.User, error) {
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+
+	if !models.IsValidRole(input.Role) {
+		return nil, ErrInvalidRole
+	}
+
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.db.CreateUser(username, strings.TrimSpace(input.FullName), input.Role, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(int(id))
+}
+
+func (s *UserService) GetUsers() ([]models.User, error) {
+	return s.db.GetUsers()
+}
+
+func (s *UserService) GetUser(id int) (*models.User, error) {
+	user, err := s.db.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) SetActive(id int, active bool) error {
+	return s.db.SetUserActive(id, active)
+}
+
+func (s *UserService) SetRole(id int, role string) error {
+	if !models.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	return s.db.UpdateUserRole(id, role)
+}
+
+// ResetPassword sets a new password for the user. If password is empty a
+// random one is generated; the plain password is returned so it can be
+// handed over to the user once.
+func (s *UserService) ResetPassword(id int, password string) (string, error) {
+	if password == "" {
+		generated, err := generatePassword()
+		if err != nil {
+			return "", err
+		}
+		password = generated
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.db.UpdateUserPassword(id, hash); err != nil {
+		return "", err
+	}
+
+	return password, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
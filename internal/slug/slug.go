@@ -0,0 +1,74 @@
+// Package slug builds URL-friendly identifiers from Russian, Kazakh and
+// Latin titles.
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the maximum length of a generated slug
+const MaxLength = 80
+
+// translit maps lowercase Cyrillic letters (Russian and Kazakh) to Latin
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Kazakh letters
+	'ә': "a", 'ғ': "gh", 'қ': "q", 'ң': "ng", 'ө': "o", 'ұ': "u", 'ү': "u",
+	'һ': "h", 'і': "i",
+}
+
+// Make converts s to a lowercase slug of Latin letters, digits and hyphens,
+// e.g. "Победа в олимпиаде 2024!" becomes "pobeda-v-olimpiade-2024".
+// The result is empty if s contains nothing that can be transliterated.
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range strings.ToLower(s) {
+		var part string
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			part = string(r)
+		default:
+			if t, ok := translit[r]; ok {
+				part = t
+			} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				// Letters of other alphabets are dropped rather than turned into separators
+				continue
+			}
+		}
+
+		if part == "" {
+			// Hard and soft signs vanish, everything else separates words
+			if _, ok := translit[r]; !ok && b.Len() > 0 {
+				hyphen = true
+			}
+			continue
+		}
+
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(part)
+	}
+
+	return truncate(b.String(), MaxLength)
+}
+
+// truncate shortens slug to at most max bytes, cutting at a hyphen when possible
+func truncate(slug string, max int) string {
+	if len(slug) <= max {
+		return slug
+	}
+	slug = slug[:max]
+	if i := strings.LastIndexByte(slug, '-'); i > max/2 {
+		slug = slug[:i]
+	}
+	return strings.Trim(slug, "-")
+}
@@ -0,0 +1,43 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Победа в олимпиаде 2024!", "pobeda-v-olimpiade-2024"},
+		{"Щедрый подъезд, объявление", "shchedryy-podezd-obyavlenie"},
+		{"Қазақ тілі күні", "qazaq-tili-kuni"},
+		{"  Open Day -- 1 June  ", "open-day-1-june"},
+		{"Ёлка и «Новый год»", "elka-i-novyy-god"},
+		{"日本語 день", "den"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.title); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestMakeTruncatesAtHyphen(t *testing.T) {
+	title := strings.Repeat("Очень длинный заголовок ", 10)
+	got := Make(title)
+
+	if len(got) > MaxLength {
+		t.Fatalf("Make returned %d bytes, want at most %d", len(got), MaxLength)
+	}
+	words := map[string]bool{"ochen": true, "dlinnyy": true, "zagolovok": true}
+	for _, part := range strings.Split(got, "-") {
+		if !words[part] {
+			t.Errorf("Make(%q) = %q, cut inside a word", title, got)
+			break
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package storage keeps uploaded files under names generated by the server.
+// Names sent by the client are kept only as metadata.
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxNameLength limits original file names kept as metadata (in bytes)
+const maxNameLength = 255
+
+// generatedName matches names made by NewName, optionally with a size suffix
+// of an image variant ("…_thumb.webp")
+var generatedName = regexp.MustCompile(`^[0-9a-f]{32}(_[a-z]+)?(\.[0-9a-z]+)?$`)
+
+// NewName returns a random base name of 32 hex characters. Callers append
+// the extension and, for image variants, a size suffix.
+func NewName() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// IsGenerated reports whether a stored file name was produced by NewName
+func IsGenerated(name string) bool {
+	return generatedName.MatchString(name)
+}
+
+// CleanName turns a client-supplied file name into a safe display name: the
+// directory part, control characters and surrounding spaces are removed and
+// the length is limited. Cyrillic and other letters are kept.
+func CleanName(name string) string {
+	// Browsers on Windows may send the full path
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '/' || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "." || name == ".." {
+		name = ""
+	}
+	if len(name) > maxNameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = truncateUTF8(strings.TrimSuffix(name, ext), maxNameLength-len(ext)) + ext
+	}
+	return name
+}
+
+// truncateUTF8 cuts s to at most max bytes without splitting a character
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
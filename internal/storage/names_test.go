@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCleanName(t *testing.T) {
+	long := strings.Repeat("я", 200) + ".pdf"
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Отчет за 2024 год.pdf", "Отчет за 2024 год.pdf"},
+		{`C:\Users\ivan\Документы\план.docx`, "план.docx"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\school.db`, "school.db"},
+		{"/", ""},
+		{"..", ""},
+		{"../..", ""},
+		{"", ""},
+		{"  report\x00\x1b.pdf\n ", "report.pdf"},
+		{"a\xffb.txt", "ab.txt"},
+		{long, strings.Repeat("я", 125) + ".pdf"},
+	}
+
+	for _, tt := range tests {
+		got := CleanName(tt.name)
+		if got != tt.want {
+			t.Errorf("CleanName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if len(got) > maxNameLength || !utf8.ValidString(got) {
+			t.Errorf("CleanName(%q) = %q is too long or not valid UTF-8", tt.name, got)
+		}
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	name, err := NewName()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name, true},
+		{name + ".pdf", true},
+		{name + "_thumb.webp", true},
+		{"1700000000_photo.jpg", false},
+		{strings.ToUpper(name) + ".pdf", false},
+		{name + "/../x.pdf", false},
+		{"photo.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsGenerated(tt.name); got != tt.want {
+			t.Errorf("IsGenerated(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned for keys that could escape the storage root
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files. Keys are slash-separated paths relative to
+// the upload root, e.g. "documents/3f9c….pdf"; the public URL of a key is
+// "/uploads/" + key whatever the backend.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing an existing file
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Open returns the file for reading; a missing file is an os.ErrNotExist error
+	Open(key string) (*Object, error)
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(key string) error
+}
+
+// Object is a stored file opened for reading. For local files the reader
+// also implements io.Seeker, which allows range requests.
+type Object struct {
+	io.ReadCloser
+	Size        int64
+	ModTime     time.Time
+	ContentType string // empty if the backend doesn't keep it
+}
+
+// URLPrefix is the path under which uploaded files are served
+const URLPrefix = "/uploads/"
+
+// URL returns the public address of a key
+func URL(key string) string {
+	return URLPrefix + key
+}
+
+// KeyFromURL returns the key of an uploaded file address ("/uploads/…") and
+// false for anything else, e.g. an external image URL
+func KeyFromURL(url string) (string, bool) {
+	if !strings.HasPrefix(url, URLPrefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, URLPrefix)
+	return key, ValidKey(key)
+}
+
+// ValidKey reports whether key is a relative slash-separated path that stays
+// inside the storage root
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package storage
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"3f9c.jpg", true},
+		{"documents/3f9c.pdf", true},
+		{"documents/.hidden", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../school.db", false},
+		{"documents/../../school.db", false},
+		{"documents/..", false},
+		{"./3f9c.jpg", false},
+		{"documents//3f9c.pdf", false},
+		{"documents/", false},
+		{`documents\..\school.db`, false},
+		{`..\school.db`, false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeyFromURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   string
+		wantOK bool
+	}{
+		{"/uploads/3f9c.jpg", "3f9c.jpg", true},
+		{"/uploads/documents/3f9c.pdf", "documents/3f9c.pdf", true},
+		{"/uploads/../school.db", "../school.db", false},
+		{"/uploads/", "", false},
+		{"https://example.com/uploads/3f9c.jpg", "", false},
+		{"/images/logo.png", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := KeyFromURL(tt.url)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("KeyFromURL(%q) = %q, %v, want %q, %v", tt.url, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
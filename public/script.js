@@ -338,9 +338,10 @@ document.addEventListener('DOMContentLoaded', function() {
             
             try {
                 // Try to load from API
-                const response = await fetch('/api/news');
+                const response = await fetch('/api/news?view=list&limit=12');
                 if (response.ok) {
-                    const articles = await response.json();
+                    const data = await response.json();
+                    const articles = data.items;
                     if (Array.isArray(articles) && articles.length > 0) {
                         this.createCarousel(articles, container);
                         return;
@@ -475,25 +476,53 @@ document.addEventListener('DOMContentLoaded', function() {
                 day: 'numeric'
             });
             
-            const excerpt = article.content && article.content.length > 100 
-                ? article.content.substring(0, 100) + '...' 
-                : article.content || 'Читать полностью...';
+            const text = article.excerpt || article.content;
+            const excerpt = this.escapeHtml(text && text.length > 100 
+                ? text.substring(0, 100) + '...' 
+                : text || 'Читать полностью...');
+            const title = this.escapeHtml(article.title || 'Без заголовка');
                 
             const imageHTML = article.image_url 
-                ? `<img src="${article.image_url}" alt="${article.title}" class="news-card-image" loading="lazy">`
+                ? this.createNewsImage(article, title)
                 : `<div class="news-card-image" style="background: linear-gradient(135deg, #f8f9fa 0%, #e9ecef 100%); display: flex; align-items: center; justify-content: center; color: #6c757d; font-size: 3rem;"><i class="fas fa-newspaper"></i></div>`;
             
             return `
-                <div class="news-card" onclick="window.location.href='news_article.html?id=${article.id}'">
+                <div class="news-card" onclick="window.location.href='${article.url || 'news_article.html?id=' + article.id}'">
                     ${imageHTML}
                     <div class="news-card-content">
-                        <h3 class="news-card-title">${article.title || 'Без заголовка'}</h3>
+                        <h3 class="news-card-title">${title}</h3>
                         <p class="news-card-excerpt">${excerpt}</p>
                         <div class="news-card-date">${date}</div>
                     </div>
                 </div>
             `;
         },
+
+        // Cards use the resized variants of the photo (WebP where supported)
+        createNewsImage(article, title) {
+            const variants = article.image_variants || [];
+            const srcset = type => variants
+                .filter(v => v.type === type)
+                .map(v => `${this.escapeHtml(v.url)} ${v.width}w`)
+                .join(', ');
+            const src = this.escapeHtml(article.image_url);
+            if (!variants.length) {
+                return `<img src="${src}" alt="${title}" class="news-card-image" loading="lazy">`;
+            }
+            const fallback = variants.find(v => v.type !== 'image/webp');
+            const sizes = '(max-width: 768px) 100vw, 400px';
+            return `<picture>
+                        <source type="image/webp" srcset="${srcset('image/webp')}" sizes="${sizes}">
+                        <img src="${src}" srcset="${fallback ? srcset(fallback.type) : ''}" sizes="${sizes}" alt="${title}" class="news-card-image" loading="lazy">
+                    </picture>`;
+        },
+
+        // News titles and excerpts are plain text and must not be parsed as HTML
+        escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML.replace(/"/g, '&quot;');
+        },
         
         bindEvents() {
             const prevBtn = document.getElementById('prev-btn');